@@ -0,0 +1,68 @@
+package log
+
+import (
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// zoneClock is a Clock which returns the times in the location.
+type zoneClock struct {
+	Clock
+	loc *time.Location
+}
+
+func (c zoneClock) Now() time.Time {
+	return c.Clock.Now().In(c.loc)
+}
+
+// clock returns the Clock of the entry times, the filenames and the
+// rotation, the times are in the TimeZone if it is set.
+func (o *Options) clock() Clock {
+	clock := o.Clock
+	if clock == nil {
+		clock = zapcore.DefaultClock
+	}
+	if o.TimeZone == "" {
+		return clock
+	}
+	loc, err := time.LoadLocation(o.TimeZone)
+	if err != nil {
+		// the time zone is validated by Options.Validate
+		return clock
+	}
+	return zoneClock{Clock: clock, loc: loc}
+}
+
+// filenameEncoder returns the FilenameEncoder of the options, the
+// TimeFilenameEncoder is called with the time of the clock. The built-in
+// FilenameEncoders are replaced by their TimeFilenameEncoders, so that they
+// follow the clock too.
+func (o *Options) filenameEncoder(clock Clock) FilenameEncoder {
+	encoder := o.TimeFilenameEncoder
+	if encoder == nil {
+		encoder = builtinTimeFilenameEncoder(o.FilenameEncoder)
+	}
+	if encoder == nil {
+		return o.FilenameEncoder
+	}
+	return func() string {
+		return encoder(clock.Now())
+	}
+}
+
+// builtinTimeFilenameEncoder returns the TimeFilenameEncoder of the built-in
+// FilenameEncoder, or nil if it's not a built-in one.
+func builtinTimeFilenameEncoder(encoder FilenameEncoder) TimeFilenameEncoder {
+	switch {
+	case encoder == nil:
+		return nil
+	case funcEqual(encoder, FilenameEncoder(DefaultFilenameEncoder)):
+		return DailyTimeFilenameEncoder
+	case funcEqual(encoder, FilenameEncoder(HourlyFilenameEncoder)):
+		return HourlyTimeFilenameEncoder
+	case funcEqual(encoder, FilenameEncoder(MinutelyFilenameEncoder)):
+		return MinutelyTimeFilenameEncoder
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+type fixedClock struct {
+	t time.Time
+}
+
+func (c fixedClock) Now() time.Time { return c.t }
+
+func (c fixedClock) NewTicker(d time.Duration) *time.Ticker { return time.NewTicker(d) }
+
+func TestTimeZoneAndClock(t *testing.T) {
+	dir := t.TempDir()
+	opts := NewOptions()
+	opts.DisableConsole = true
+	opts.DisableFile = false
+	opts.DisableFileTime = false
+	opts.Output = dir
+	opts.TimeFilenameEncoder = DailyTimeFilenameEncoder
+	opts.TimeEncoder = DefaultTimeEncoder
+	opts.TimeZone = "Asia/Tokyo"
+	opts.Clock = fixedClock{t: time.Date(2006, 1, 2, 23, 30, 0, 0, time.UTC)}
+	assert.Equal(t, 0, len(opts.Validate()))
+
+	l := New(opts)
+	l.Infot("hello")
+	assert.NoError(t, l.Close())
+
+	filename := filepath.Base(os.Args[0]) + "-20060103.log"
+	assert.Equal(t, filepath.Join(dir, filename), l.EncodedFilename())
+	content, err := os.ReadFile(filepath.Join(dir, filename))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"level":"INFO","time":"2006-01-03 08:30:00.000","msg":"hello"}`+"\n", string(content))
+
+	opts.TimeZone = "Mars/Olympus"
+	errs := opts.Validate()
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, "unknown time zone Mars/Olympus", errs[0].Error())
+}
+
+func TestFilenameEncoderClock(t *testing.T) {
+	name := filepath.Base(os.Args[0])
+	opts := NewOptions()
+	opts.TimeZone = "Asia/Tokyo"
+	opts.Clock = fixedClock{t: time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)}
+	clock := opts.clock()
+
+	opts.TimeFilenameEncoder = DailyTimeFilenameEncoder
+	assert.Equal(t, name+"-20060103.log", opts.filenameEncoder(clock)())
+	opts.TimeFilenameEncoder = HourlyTimeFilenameEncoder
+	assert.Equal(t, name+"-20060103-00.log", opts.filenameEncoder(clock)())
+	opts.TimeFilenameEncoder = MinutelyTimeFilenameEncoder
+	assert.Equal(t, name+"-20060103-0004.log", opts.filenameEncoder(clock)())
+	// the custom encoders get the time in the time zone
+	opts.TimeFilenameEncoder = func(t time.Time) string {
+		return "app-" + t.Format("2006010215") + ".log"
+	}
+	assert.Equal(t, "app-2006010300.log", opts.filenameEncoder(clock)())
+
+	opts.TimeFilenameEncoder = nil
+	opts.FilenameEncoder = func() string { return "test.log" }
+	assert.Equal(t, "test.log", opts.filenameEncoder(clock)())
+	opts.FilenameEncoder = nil
+	assert.Nil(t, opts.filenameEncoder(clock))
+}
+
+func TestDevEncoderClock(t *testing.T) {
+	start := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	opts := NewOptions()
+	opts.Clock = fixedClock{t: start}
+	opts.ConsoleRelativeTime = true
+	enc := newEncoder(opts, FormatDev, zapcore.EncoderConfig{TimeKey: "time"}, false)
+	buf, err := enc.EncodeEntry(zapcore.Entry{Time: start.Add(1500 * time.Millisecond)}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "+1.500s\n", buf.String())
+}
+
+func TestBuiltinFilenameEncoderClock(t *testing.T) {
+	name := filepath.Base(os.Args[0])
+	dir := t.TempDir()
+	opts := NewOptions()
+	opts.DisableConsole = true
+	opts.DisableFile = false
+	opts.Output = dir
+	opts.TimeZone = "Asia/Tokyo"
+	opts.Clock = fixedClock{t: time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)}
+	clock := opts.clock()
+
+	opts.FilenameEncoder = DefaultFilenameEncoder
+	assert.Equal(t, name+"-20060103.log", opts.filenameEncoder(clock)())
+	opts.FilenameEncoder = HourlyFilenameEncoder
+	assert.Equal(t, name+"-20060103-00.log", opts.filenameEncoder(clock)())
+	opts.FilenameEncoder = MinutelyFilenameEncoder
+	assert.Equal(t, name+"-20060103-0004.log", opts.filenameEncoder(clock)())
+
+	l := New(opts)
+	l.Infot("hello")
+	assert.NoError(t, l.Close())
+	assert.Equal(t, filepath.Join(dir, name+"-20060103-0004.log"), l.EncodedFilename())
+	assert.FileExists(t, l.EncodedFilename())
+}
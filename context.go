@@ -0,0 +1,154 @@
+package log
+
+import (
+	"context"
+)
+
+type (
+	loggerContextKey struct{}
+	fieldsContextKey struct{}
+)
+
+// NewContext returns a copy of the ctx which carries the logger, the logger
+// is returned by FromContext and used by the package level Context
+// functions, e.g. InfoContext.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, l)
+}
+
+// FromContext returns the logger carried by the ctx, or the global logger if
+// the ctx carries no logger. The fields of the ctx are not added to the
+// returned logger, they are added by the Context methods of the logger.
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerContextKey{}).(*Logger); ok && l != nil {
+			return l
+		}
+	}
+	return _globalL
+}
+
+// WithContextFields returns a copy of the ctx which carries the fields in
+// addition to the fields of the ctx. The fields are added to the entries
+// logged by the Context methods and functions, e.g.
+//
+//	ctx = log.WithContextFields(ctx, log.String("request_id", id))
+//	log.InfoContext(ctx, "user loaded", "user_id", uid)
+func WithContextFields(ctx context.Context, fields ...Field) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	prev := ContextFields(ctx)
+	merged := make([]Field, 0, len(prev)+len(fields))
+	merged = append(merged, prev...)
+	merged = append(merged, fields...)
+	return context.WithValue(ctx, fieldsContextKey{}, merged)
+}
+
+// ContextFields returns the fields carried by the ctx.
+func ContextFields(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsContextKey{}).([]Field)
+	return fields
+}
+
+// contextArgs returns the fields extracted from the ctx and the fields of
+// the ctx followed by the keysAndValues, which are the arguments of the
+// sugared logger, the errors are encoded by NamedErr, see errorArgs.
+func contextArgs(ctx context.Context, keysAndValues []interface{}) []interface{} {
+	keysAndValues = errorArgs(keysAndValues)
+	extracted, fields := extractContext(ctx), ContextFields(ctx)
+	if len(extracted) == 0 && len(fields) == 0 {
+		return keysAndValues
+	}
+	args := make([]interface{}, 0, len(extracted)+len(fields)+len(keysAndValues))
+	for _, f := range extracted {
+		args = append(args, f)
+	}
+	for _, f := range fields {
+		args = append(args, f)
+	}
+	return append(args, keysAndValues...)
+}
+
+// DebugContext logs a message at DebugLevel with the fields of the ctx.
+func (l *Logger) DebugContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.sugared.Debugw(msg, contextArgs(ctx, keysAndValues)...)
+}
+
+// InfoContext logs a message at InfoLevel with the fields of the ctx.
+func (l *Logger) InfoContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.sugared.Infow(msg, contextArgs(ctx, keysAndValues)...)
+}
+
+// WarnContext logs a message at WarnLevel with the fields of the ctx.
+func (l *Logger) WarnContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.sugared.Warnw(msg, contextArgs(ctx, keysAndValues)...)
+}
+
+// ErrorContext logs a message at ErrorLevel with the fields of the ctx.
+func (l *Logger) ErrorContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.sugared.Errorw(msg, contextArgs(ctx, keysAndValues)...)
+}
+
+// DPanicContext logs a message at DPanicLevel with the fields of the ctx, it
+// panics in development.
+func (l *Logger) DPanicContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.sugared.DPanicw(msg, contextArgs(ctx, keysAndValues)...)
+}
+
+// PanicContext logs a message at PanicLevel with the fields of the ctx, then
+// panics.
+func (l *Logger) PanicContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.sugared.Panicw(msg, contextArgs(ctx, keysAndValues)...)
+}
+
+// FatalContext logs a message at FatalLevel with the fields of the ctx, then
+// calls os.Exit(1).
+func (l *Logger) FatalContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l.sugared.Fatalw(msg, contextArgs(ctx, keysAndValues)...)
+}
+
+// DebugContext logs a message at DebugLevel with the logger and the fields
+// of the ctx.
+func DebugContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	FromContext(ctx).DebugContext(ctx, msg, keysAndValues...)
+}
+
+// InfoContext logs a message at InfoLevel with the logger and the fields of
+// the ctx.
+func InfoContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	FromContext(ctx).InfoContext(ctx, msg, keysAndValues...)
+}
+
+// WarnContext logs a message at WarnLevel with the logger and the fields of
+// the ctx.
+func WarnContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	FromContext(ctx).WarnContext(ctx, msg, keysAndValues...)
+}
+
+// ErrorContext logs a message at ErrorLevel with the logger and the fields
+// of the ctx.
+func ErrorContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	FromContext(ctx).ErrorContext(ctx, msg, keysAndValues...)
+}
+
+// DPanicContext logs a message at DPanicLevel with the logger and the
+// fields of the ctx, it panics in development.
+func DPanicContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	FromContext(ctx).DPanicContext(ctx, msg, keysAndValues...)
+}
+
+// PanicContext logs a message at PanicLevel with the logger and the fields
+// of the ctx, then panics.
+func PanicContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	FromContext(ctx).PanicContext(ctx, msg, keysAndValues...)
+}
+
+// FatalContext logs a message at FatalLevel with the logger and the fields
+// of the ctx, then calls os.Exit(1).
+func FatalContext(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	FromContext(ctx).FatalContext(ctx, msg, keysAndValues...)
+}
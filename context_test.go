@@ -0,0 +1,87 @@
+package log
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newContextTestLogger(t *testing.T) *Logger {
+	opts := NewOptions()
+	opts.DisableConsole = true
+	opts.DisableFile = false
+	opts.DisableFileTime = true
+	opts.DisableFileCaller = false
+	opts.FileLevel = DebugLevel.String()
+	opts.Output = t.TempDir()
+	return New(opts)
+}
+
+func readContextTestLogger(t *testing.T, l *Logger) []string {
+	assert.NoError(t, l.Close())
+	content, err := os.ReadFile(l.EncodedFilename())
+	assert.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(content)), "\n")
+}
+
+func TestContextFields(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, ContextFields(ctx))
+	assert.Equal(t, ctx, WithContextFields(ctx))
+
+	ctx1 := WithContextFields(ctx, String("request_id", "r1"))
+	ctx2 := WithContextFields(ctx1, String("user_id", "u1"))
+	assert.Equal(t, []Field{String("request_id", "r1")}, ContextFields(ctx1))
+	assert.Equal(t, []Field{String("request_id", "r1"), String("user_id", "u1")}, ContextFields(ctx2))
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, L(), FromContext(context.Background()))
+
+	l := newContextTestLogger(t)
+	defer func() { _ = l.Close() }()
+	assert.Equal(t, l, FromContext(NewContext(context.Background(), l)))
+}
+
+func TestContextMethods(t *testing.T) {
+	l := newContextTestLogger(t)
+	ctx := WithContextFields(context.Background(), String("request_id", "r1"))
+
+	l.DebugContext(ctx, "debug", "user_id", "u1")
+	l.InfoContext(ctx, "info")
+	l.WarnContext(context.Background(), "warn", "user_id", "u1")
+	// the package level functions use the logger of the ctx
+	ErrorContext(NewContext(ctx, l), "error", Int("attempt", 2))
+
+	lines := readContextTestLogger(t, l)
+	assert.Equal(t, 4, len(lines))
+	assert.Equal(t, []string{
+		`{"level":"DEBUG","caller":"log/context_test.go:53","msg":"debug","request_id":"r1","user_id":"u1"}`,
+		`{"level":"INFO","caller":"log/context_test.go:54","msg":"info","request_id":"r1"}`,
+		`{"level":"WARN","caller":"log/context_test.go:55","msg":"warn","user_id":"u1"}`,
+	}, lines[:3])
+	assert.True(t, strings.HasSuffix(lines[3], `"msg":"error","request_id":"r1","attempt":2}`), lines[3])
+}
+
+var _ ContextLogger = (*Logger)(nil)
+
+func TestDPanicContext(t *testing.T) {
+	l := newContextTestLogger(t)
+	ctx := WithContextFields(NewContext(context.Background(), l), String("request_id", "r1"))
+	assert.NotPanics(t, func() {
+		l.DPanicContext(ctx, "dpanic", "key", 1)
+		DPanicContext(ctx, "global dpanic")
+	})
+	lines := readContextTestLogger(t, l)
+	assert.Equal(t, 2, len(lines))
+	assert.Contains(t, lines[0], `"msg":"dpanic","request_id":"r1","key":1}`)
+	assert.Contains(t, lines[1], `"msg":"global dpanic","request_id":"r1"}`)
+
+	opts := NewOptions()
+	opts.Development = true
+	l = New(opts)
+	assert.Panics(t, func() { l.DPanicContext(context.Background(), "dpanic") })
+}
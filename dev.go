@@ -0,0 +1,482 @@
+package log
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	devLevelWidth   = 5
+	devCallerWidth  = 24
+	devMessageWidth = 40
+	devIndent       = "    "
+)
+
+const (
+	colorReset   = "\x1b[0m"
+	colorBold    = "\x1b[1m"
+	colorFaint   = "\x1b[2m"
+	colorRed     = "\x1b[31m"
+	colorGreen   = "\x1b[32m"
+	colorYellow  = "\x1b[33m"
+	colorBlue    = "\x1b[34m"
+	colorMagenta = "\x1b[35m"
+	colorCyan    = "\x1b[36m"
+)
+
+var _devPool = buffer.NewPool()
+
+// DevEncoderConfig configures the encoder of FormatDev.
+type DevEncoderConfig struct {
+	// Color whether to colorize the levels, keys and values
+	Color bool
+	// RelativeTime whether to encode the time as the elapsed time since the
+	// Start, e.g. +1.250s
+	RelativeTime bool
+	// Start the start time of the RelativeTime, default the time when the
+	// encoder is created
+	Start time.Time
+	// MultilineFields whether to encode every field on an indented
+	// continuation line
+	MultilineFields bool
+	// Hyperlinks whether to encode the callers as OSC 8 hyperlinks to the
+	// source files, it only takes effect if Color is enabled
+	Hyperlinks bool
+}
+
+type devKind int
+
+const (
+	devString devKind = iota
+	devNumber
+	devBool
+	devTime
+	devNull
+	devObject
+	devError
+)
+
+type devField struct {
+	key  string
+	val  string
+	kind devKind
+}
+
+// devEncoder is a zapcore.Encoder for the developers reading the console,
+// the level, caller and message are aligned in columns, e.g.
+//
+//	15:04:05.000 INFO  app/main.go:10            started                                  addr=:8080 tls=false
+//	15:04:05.120 ERROR app/server.go:42          request failed                           status=500
+//	    main.run
+//	        /src/app/server.go:42
+//
+// The keys and values are colorized by type, the stacktraces and the
+// multi-line values are encoded on indented continuation lines.
+type devEncoder struct {
+	*zapcore.EncoderConfig
+	dev   DevEncoderConfig
+	start time.Time
+
+	fields   []devField
+	prefixes []string
+	// isError marks the values added by the error fields
+	isError bool
+}
+
+// NewDevEncoder creates an encoder which encodes the entries in a
+// human-friendly format for the development.
+func NewDevEncoder(cfg zapcore.EncoderConfig, dev DevEncoderConfig) zapcore.Encoder {
+	if cfg.LineEnding == "" {
+		cfg.LineEnding = zapcore.DefaultLineEnding
+	}
+	start := dev.Start
+	if start.IsZero() {
+		start = time.Now()
+	}
+	return &devEncoder{
+		EncoderConfig: &cfg,
+		dev:           dev,
+		start:         start,
+	}
+}
+
+// isColorTerminal reports whether the colors should be written to the file.
+// NO_COLOR disables the colors and FORCE_COLOR enables them, otherwise the
+// colors are enabled if the file is a terminal.
+func isColorTerminal(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if force := os.Getenv("FORCE_COLOR"); force != "" {
+		return force != "0" && force != "false"
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+func (enc *devEncoder) Clone() zapcore.Encoder {
+	return enc.clone()
+}
+
+func (enc *devEncoder) clone() *devEncoder {
+	return &devEncoder{
+		EncoderConfig: enc.EncoderConfig,
+		dev:           enc.dev,
+		start:         enc.start,
+		fields:        append([]devField(nil), enc.fields...),
+		prefixes:      append([]string(nil), enc.prefixes...),
+	}
+}
+
+func (enc *devEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := enc.clone()
+	for i := range fields {
+		_, final.isError = errorOf(fields[i])
+		fields[i].AddTo(final)
+	}
+	final.isError = false
+
+	buf := _devPool.Get()
+	if final.TimeKey != "" && !ent.Time.IsZero() {
+		final.appendColumn(buf, final.formatTime(ent.Time), 0, colorFaint)
+	}
+	if final.LevelKey != "" && final.EncodeLevel != nil {
+		level := encodeString(func(arr zapcore.PrimitiveArrayEncoder) {
+			final.EncodeLevel(ent.Level, arr)
+		}, ent.Level.CapitalString())
+		final.appendColumn(buf, level, devLevelWidth, levelColor(ent.Level))
+	}
+	if ent.LoggerName != "" && final.NameKey != "" {
+		final.appendColumn(buf, ent.LoggerName, 0, colorBlue)
+	}
+	if ent.Caller.Defined && final.CallerKey != "" && final.EncodeCaller != nil {
+		caller := encodeString(func(arr zapcore.PrimitiveArrayEncoder) {
+			final.EncodeCaller(ent.Caller, arr)
+		}, ent.Caller.TrimmedPath())
+		final.appendCaller(buf, ent.Caller, caller)
+	}
+	if final.FunctionKey != "" && ent.Caller.Function != "" {
+		final.appendColumn(buf, ent.Caller.Function, 0, colorFaint)
+	}
+	if final.MessageKey != "" {
+		width := 0
+		if !final.dev.MultilineFields && final.hasInlineFields() {
+			width = devMessageWidth
+		}
+		final.appendColumn(buf, ent.Message, width, colorBold)
+	}
+
+	var blocks []devField
+	for _, f := range final.fields {
+		if f.kind != devObject && strings.Contains(f.val, "\n") {
+			blocks = append(blocks, f)
+			continue
+		}
+		if final.dev.MultilineFields {
+			buf.AppendString(final.LineEnding)
+			buf.AppendString(devIndent)
+		} else if buf.Len() > 0 {
+			buf.AppendByte(' ')
+		}
+		final.appendField(buf, f)
+	}
+	for _, f := range blocks {
+		buf.AppendString(final.LineEnding)
+		buf.AppendString(devIndent)
+		final.appendColored(buf, f.key+":", keyColor(f.kind))
+		for _, line := range strings.Split(strings.TrimRight(f.val, "\n"), "\n") {
+			buf.AppendString(final.LineEnding)
+			buf.AppendString(devIndent + devIndent)
+			final.appendColored(buf, line, valueColor(f.kind))
+		}
+	}
+	if ent.Stack != "" && final.StacktraceKey != "" {
+		final.appendStack(buf, ent.Stack)
+	}
+	buf.AppendString(final.LineEnding)
+	return buf, nil
+}
+
+// hasInlineFields reports whether any field is encoded on the entry line.
+func (enc *devEncoder) hasInlineFields() bool {
+	for _, f := range enc.fields {
+		if f.kind == devObject || !strings.Contains(f.val, "\n") {
+			return true
+		}
+	}
+	return false
+}
+
+func (enc *devEncoder) formatTime(t time.Time) string {
+	if enc.dev.RelativeTime {
+		return fmt.Sprintf("+%.3fs", t.Sub(enc.start).Seconds())
+	}
+	if enc.EncodeTime == nil {
+		return t.Format("15:04:05.000")
+	}
+	return encodeString(func(arr zapcore.PrimitiveArrayEncoder) {
+		enc.EncodeTime(t, arr)
+	}, t.Format("15:04:05.000"))
+}
+
+// appendColumn appends the text padded to the width, the padding is not
+// colorized.
+func (enc *devEncoder) appendColumn(buf *buffer.Buffer, text string, width int, color string) {
+	if buf.Len() > 0 {
+		buf.AppendByte(' ')
+	}
+	enc.appendColored(buf, text, color)
+	appendPadding(buf, text, width)
+}
+
+func (enc *devEncoder) appendCaller(buf *buffer.Buffer, caller zapcore.EntryCaller, text string) {
+	if buf.Len() > 0 {
+		buf.AppendByte(' ')
+	}
+	if enc.dev.Color && enc.dev.Hyperlinks {
+		file := caller.File
+		if abs, err := filepath.Abs(file); err == nil {
+			file = abs
+		}
+		// OSC 8 ; params ; URI ST text OSC 8 ; ; ST
+		buf.AppendString("\x1b]8;;file://")
+		buf.AppendString(filepath.ToSlash(file))
+		buf.AppendString("\x1b\\")
+		enc.appendColored(buf, text, colorFaint)
+		buf.AppendString("\x1b]8;;\x1b\\")
+	} else {
+		enc.appendColored(buf, text, colorFaint)
+	}
+	appendPadding(buf, text, devCallerWidth)
+}
+
+func (enc *devEncoder) appendField(buf *buffer.Buffer, f devField) {
+	enc.appendColored(buf, f.key+"=", keyColor(f.kind))
+	val := f.val
+	if f.kind == devString || f.kind == devError {
+		if needsQuote(val) {
+			val = strconv.Quote(val)
+		}
+	}
+	enc.appendColored(buf, val, valueColor(f.kind))
+}
+
+// appendStack appends the stacktrace of zap, the functions and the files
+// are on the alternate lines, the files are indented with a tab.
+func (enc *devEncoder) appendStack(buf *buffer.Buffer, stack string) {
+	for _, line := range strings.Split(strings.TrimRight(stack, "\n"), "\n") {
+		buf.AppendString(enc.LineEnding)
+		if strings.HasPrefix(line, "\t") {
+			buf.AppendString(devIndent + devIndent)
+			enc.appendColored(buf, strings.TrimLeft(line, "\t"), colorFaint)
+			continue
+		}
+		buf.AppendString(devIndent)
+		enc.appendColored(buf, line, colorRed)
+	}
+}
+
+func (enc *devEncoder) appendColored(buf *buffer.Buffer, text, color string) {
+	if !enc.dev.Color || color == "" {
+		buf.AppendString(text)
+		return
+	}
+	buf.AppendString(color)
+	buf.AppendString(text)
+	buf.AppendString(colorReset)
+}
+
+func appendPadding(buf *buffer.Buffer, text string, width int) {
+	for n := utf8.RuneCountInString(text); n < width; n++ {
+		buf.AppendByte(' ')
+	}
+}
+
+func levelColor(lvl Level) string {
+	switch {
+	case lvl < InfoLevel:
+		return colorMagenta
+	case lvl == InfoLevel:
+		return colorBlue
+	case lvl == WarnLevel:
+		return colorYellow
+	default:
+		return colorRed
+	}
+}
+
+func keyColor(kind devKind) string {
+	if kind == devError {
+		return colorRed
+	}
+	return colorCyan
+}
+
+func valueColor(kind devKind) string {
+	switch kind {
+	case devNumber:
+		return colorMagenta
+	case devBool:
+		return colorYellow
+	case devTime:
+		return colorBlue
+	case devNull:
+		return colorFaint
+	case devError:
+		return colorRed
+	case devString:
+		return colorGreen
+	}
+	return ""
+}
+
+// encodeString encodes a value with the given zapcore encoder function, the
+// appended values are joined with commas. If the function appends nothing,
+// the fallback value is used.
+func encodeString(fn func(zapcore.PrimitiveArrayEncoder), fallback string) string {
+	arr := &logfmtValueEncoder{}
+	fn(arr)
+	if len(arr.elems) == 0 {
+		return fallback
+	}
+	return strings.Join(arr.elems, ",")
+}
+
+func (enc *devEncoder) add(key, val string, kind devKind) {
+	if len(enc.prefixes) > 0 {
+		key = strings.Join(enc.prefixes, ".") + "." + key
+	}
+	if enc.isError && kind == devString {
+		kind = devError
+	}
+	enc.fields = append(enc.fields, devField{key: key, val: val, kind: kind})
+}
+
+// addJSON adds the value encoded as compact JSON by the marshal function.
+func (enc *devEncoder) addJSON(key string, marshal func(zapcore.ObjectEncoder) error) error {
+	je := zapcore.NewJSONEncoder(fieldsEncoderConfig(*enc.EncoderConfig))
+	err := marshal(je)
+	buf, _ := je.EncodeEntry(zapcore.Entry{}, nil)
+	defer buf.Free()
+	// trims the {"v": prefix and the }\n suffix
+	val := strings.TrimRight(buf.String(), "\n")
+	val = strings.TrimSuffix(strings.TrimPrefix(val, `{"v":`), "}")
+	enc.add(key, val, devObject)
+	return err
+}
+
+func (enc *devEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	return enc.addJSON(key, func(je zapcore.ObjectEncoder) error {
+		return je.AddArray("v", arr)
+	})
+}
+
+func (enc *devEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	return enc.addJSON(key, func(je zapcore.ObjectEncoder) error {
+		return je.AddObject("v", obj)
+	})
+}
+
+func (enc *devEncoder) AddReflected(key string, val interface{}) error {
+	if val == nil {
+		enc.add(key, "null", devNull)
+		return nil
+	}
+	data, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	enc.add(key, string(data), devObject)
+	return nil
+}
+
+func (enc *devEncoder) AddBinary(key string, val []byte) {
+	enc.add(key, base64.StdEncoding.EncodeToString(val), devString)
+}
+
+func (enc *devEncoder) AddByteString(key string, val []byte) {
+	enc.add(key, string(val), devString)
+}
+
+func (enc *devEncoder) AddBool(key string, val bool) {
+	enc.add(key, strconv.FormatBool(val), devBool)
+}
+
+func (enc *devEncoder) AddComplex128(key string, val complex128) {
+	enc.add(key, strconv.FormatComplex(val, 'f', -1, 128), devNumber)
+}
+
+func (enc *devEncoder) AddComplex64(key string, val complex64) {
+	enc.add(key, strconv.FormatComplex(complex128(val), 'f', -1, 64), devNumber)
+}
+
+func (enc *devEncoder) AddDuration(key string, val time.Duration) {
+	enc.add(key, val.String(), devTime)
+}
+
+func (enc *devEncoder) AddFloat64(key string, val float64) { enc.addFloat(key, val, 64) }
+
+func (enc *devEncoder) AddFloat32(key string, val float32) { enc.addFloat(key, float64(val), 32) }
+
+func (enc *devEncoder) addFloat(key string, val float64, bitSize int) {
+	switch {
+	case math.IsNaN(val):
+		enc.add(key, "NaN", devNumber)
+	case math.IsInf(val, 1):
+		enc.add(key, "+Inf", devNumber)
+	case math.IsInf(val, -1):
+		enc.add(key, "-Inf", devNumber)
+	default:
+		enc.add(key, strconv.FormatFloat(val, 'f', -1, bitSize), devNumber)
+	}
+}
+
+func (enc *devEncoder) AddInt(key string, val int) { enc.AddInt64(key, int64(val)) }
+
+func (enc *devEncoder) AddInt64(key string, val int64) {
+	enc.add(key, strconv.FormatInt(val, 10), devNumber)
+}
+
+func (enc *devEncoder) AddInt32(key string, val int32) { enc.AddInt64(key, int64(val)) }
+
+func (enc *devEncoder) AddInt16(key string, val int16) { enc.AddInt64(key, int64(val)) }
+
+func (enc *devEncoder) AddInt8(key string, val int8) { enc.AddInt64(key, int64(val)) }
+
+func (enc *devEncoder) AddString(key, val string) { enc.add(key, val, devString) }
+
+func (enc *devEncoder) AddTime(key string, val time.Time) {
+	enc.add(key, val.Format(time.RFC3339Nano), devTime)
+}
+
+func (enc *devEncoder) AddUint(key string, val uint) { enc.AddUint64(key, uint64(val)) }
+
+func (enc *devEncoder) AddUint64(key string, val uint64) {
+	enc.add(key, strconv.FormatUint(val, 10), devNumber)
+}
+
+func (enc *devEncoder) AddUint32(key string, val uint32) { enc.AddUint64(key, uint64(val)) }
+
+func (enc *devEncoder) AddUint16(key string, val uint16) { enc.AddUint64(key, uint64(val)) }
+
+func (enc *devEncoder) AddUint8(key string, val uint8) { enc.AddUint64(key, uint64(val)) }
+
+func (enc *devEncoder) AddUintptr(key string, val uintptr) { enc.AddUint64(key, uint64(val)) }
+
+func (enc *devEncoder) OpenNamespace(key string) {
+	enc.prefixes = append(enc.prefixes, key)
+}
@@ -0,0 +1,92 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestDevEncoder(t *testing.T) {
+	cfg := zapcore.EncoderConfig{
+		TimeKey:       "time",
+		LevelKey:      "level",
+		CallerKey:     "caller",
+		MessageKey:    "msg",
+		StacktraceKey: "stack",
+		EncodeLevel:   zapcore.CapitalLevelEncoder,
+		EncodeCaller:  zapcore.ShortCallerEncoder,
+	}
+	ent := zapcore.Entry{
+		Level:   ErrorLevel,
+		Time:    time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+		Message: "failed",
+		Caller:  zapcore.NewEntryCaller(0, "/src/app/main.go", 10, true),
+		Stack:   "main.run\n\t/src/app/main.go:10",
+	}
+
+	t.Run("aligned", func(t *testing.T) {
+		enc := NewDevEncoder(cfg, DevEncoderConfig{})
+		enc.OpenNamespace("req")
+		enc.AddInt("id", 1)
+		buf, err := enc.EncodeEntry(ent, []Field{
+			String("path", "/a b"),
+			Bool("ok", false),
+			Duration("elapsed", time.Second),
+			Object("user", testUser{Name: "foo", Tags: []string{"a"}}),
+			Err(errors.New("boom")),
+			String("body", "line1\nline2"),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "15:04:05.000 ERROR app/main.go:10           failed                                   "+
+			`req.id=1 req.path="/a b" req.ok=false req.elapsed=1s req.user={"name":"foo","tags":["a"]} req.error=boom`+"\n"+
+			"    req.body:\n        line1\n        line2\n"+
+			"    main.run\n        /src/app/main.go:10\n", buf.String())
+	})
+
+	t.Run("multiline fields", func(t *testing.T) {
+		enc := NewDevEncoder(zapcore.EncoderConfig{MessageKey: "msg"}, DevEncoderConfig{MultilineFields: true})
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello"}, []Field{Int("a", 1), Any("b", nil)})
+		assert.NoError(t, err)
+		assert.Equal(t, "hello\n    a=1\n    b=null\n", buf.String())
+	})
+
+	t.Run("color", func(t *testing.T) {
+		enc := NewDevEncoder(cfg, DevEncoderConfig{Color: true, Hyperlinks: true})
+		buf, err := enc.EncodeEntry(zapcore.Entry{
+			Level:   WarnLevel,
+			Message: "hello",
+			Caller:  ent.Caller,
+		}, []Field{Int("a", 1)})
+		assert.NoError(t, err)
+		assert.Equal(t, colorYellow+"WARN"+colorReset+"  "+
+			"\x1b]8;;file:///src/app/main.go\x1b\\"+colorFaint+"app/main.go:10"+colorReset+"\x1b]8;;\x1b\\"+
+			strings.Repeat(" ", 10)+" "+colorBold+"hello"+colorReset+strings.Repeat(" ", 35)+" "+
+			colorCyan+"a="+colorReset+colorMagenta+"1"+colorReset+"\n", buf.String())
+	})
+
+	t.Run("relative time", func(t *testing.T) {
+		enc := NewDevEncoder(zapcore.EncoderConfig{TimeKey: "time"}, DevEncoderConfig{RelativeTime: true}).(*devEncoder)
+		buf, err := enc.EncodeEntry(zapcore.Entry{Time: enc.start.Add(1250 * time.Millisecond)}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "+1.250s\n", buf.String())
+	})
+}
+
+func TestIsColorTerminal(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "tty")
+	assert.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("FORCE_COLOR", "")
+	assert.False(t, isColorTerminal(f))
+	t.Setenv("FORCE_COLOR", "1")
+	assert.True(t, isColorTerminal(f))
+	t.Setenv("NO_COLOR", "1")
+	assert.False(t, isColorTerminal(f))
+}
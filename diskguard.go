@@ -0,0 +1,158 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultDiskCheckInterval is the interval of the disk space check when
+// DiskCheckInterval is not set.
+const DefaultDiskCheckInterval = 10 * time.Second
+
+// diskGuard monitors the free space of the log output directory. When the
+// free space falls below MinFreeSpace, the file logging is limited to
+// LowSpaceLevel (or stopped entirely if LowSpaceLevel is empty) until the
+// space recovers.
+type diskGuard struct {
+	dir      string
+	minFree  uint64
+	interval time.Duration
+	level    Level
+	dropAll  bool
+	console  zapcore.Core
+	clock    Clock
+	free     func(path string) (uint64, error)
+
+	low      int32
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func newDiskGuard(opts *Options, console zapcore.Core) *diskGuard {
+	g := &diskGuard{
+		dir:      opts.Output,
+		minFree:  uint64(opts.MinFreeSpace) * 1024 * 1024,
+		interval: opts.DiskCheckInterval,
+		console:  console,
+		clock:    opts.clock(),
+		free:     diskFree,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if g.dir == "" {
+		g.dir = "."
+	}
+	if g.interval <= 0 {
+		g.interval = DefaultDiskCheckInterval
+	}
+	if opts.LowSpaceLevel == "" || g.level.Set(strings.ToLower(opts.LowSpaceLevel)) != nil {
+		g.dropAll = true
+	}
+	return g
+}
+
+// start checks the free space immediately, then periodically in a
+// background goroutine.
+func (g *diskGuard) start() {
+	g.check()
+	go func() {
+		defer close(g.done)
+
+		ticker := g.clock.NewTicker(g.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				g.check()
+			case <-g.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (g *diskGuard) check() {
+	free, err := g.free(g.dir)
+	if err != nil {
+		// the output directory may not be created yet
+		return
+	}
+	if free < g.minFree {
+		if atomic.CompareAndSwapInt32(&g.low, 0, 1) {
+			g.notify(WarnLevel, "low disk space, file logging is limited", free)
+		}
+		return
+	}
+	if atomic.CompareAndSwapInt32(&g.low, 1, 0) {
+		g.notify(InfoLevel, "disk space recovered, file logging is resumed", free)
+	}
+}
+
+func (g *diskGuard) notify(lvl Level, msg string, free uint64) {
+	fields := []Field{
+		String("output", g.dir),
+		Uint64("free_bytes", free),
+		Uint64("min_free_bytes", g.minFree),
+	}
+	if lvl == WarnLevel {
+		if g.dropAll {
+			fields = append(fields, String("file_level", "off"))
+		} else {
+			fields = append(fields, Stringer("file_level", g.level))
+		}
+	}
+	if g.console == nil {
+		fmt.Fprintf(os.Stderr, "%s: output=%s free=%d\n", msg, g.dir, free)
+		return
+	}
+	ent := zapcore.Entry{Level: lvl, Time: g.clock.Now(), Message: msg}
+	if ce := g.console.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// allow reports whether an entry of the given level should be written to file.
+func (g *diskGuard) allow(lvl Level) bool {
+	if atomic.LoadInt32(&g.low) == 0 {
+		return true
+	}
+	return !g.dropAll && lvl >= g.level
+}
+
+// Close stops the background disk space check.
+func (g *diskGuard) Close() error {
+	g.stopOnce.Do(func() {
+		close(g.stop)
+		<-g.done
+	})
+	return nil
+}
+
+// diskGuardCore is a zapcore.Core that drops the entries which are not
+// allowed by the diskGuard.
+type diskGuardCore struct {
+	zapcore.Core
+	guard *diskGuard
+}
+
+func (c *diskGuardCore) Enabled(lvl Level) bool {
+	return c.guard.allow(lvl) && c.Core.Enabled(lvl)
+}
+
+func (c *diskGuardCore) With(fields []Field) zapcore.Core {
+	return &diskGuardCore{Core: c.Core.With(fields), guard: c.guard}
+}
+
+func (c *diskGuardCore) Check(ent zapcore.Entry, ce *CheckedEntry) *CheckedEntry {
+	if !c.guard.allow(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
@@ -0,0 +1,66 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestDiskGuard(t *testing.T) {
+	t.Run("limit file logging to low space level", func(t *testing.T) {
+		console, logs := observer.New(DebugLevel)
+		file, fileLogs := observer.New(DebugLevel)
+
+		opts := NewOptions()
+		opts.MinFreeSpace = 10
+		opts.LowSpaceLevel = "warn"
+		g := newDiskGuard(opts, console)
+		free := uint64(100 * 1024 * 1024)
+		g.free = func(string) (uint64, error) {
+			return free, nil
+		}
+		core := &diskGuardCore{Core: file, guard: g}
+
+		g.check()
+		assert.True(t, core.Enabled(InfoLevel))
+		assert.Equal(t, 0, logs.Len())
+
+		free = 1024
+		g.check()
+		g.check()
+		assert.False(t, core.Enabled(InfoLevel))
+		assert.True(t, core.Enabled(WarnLevel))
+		assert.Nil(t, core.Check(zapcore.Entry{Level: InfoLevel}, nil))
+		if ce := core.With([]Field{String("k", "v")}).Check(zapcore.Entry{Level: ErrorLevel}, nil); ce != nil {
+			ce.Write()
+		}
+		assert.Equal(t, 1, fileLogs.Len())
+
+		free = 100 * 1024 * 1024
+		g.check()
+		assert.True(t, core.Enabled(InfoLevel))
+
+		entries := logs.AllUntimed()
+		assert.Equal(t, 2, len(entries))
+		assert.Equal(t, WarnLevel, entries[0].Level)
+		assert.Equal(t, "warn", entries[0].ContextMap()["file_level"])
+		assert.Equal(t, InfoLevel, entries[1].Level)
+	})
+
+	t.Run("stop file logging", func(t *testing.T) {
+		opts := NewOptions()
+		opts.MinFreeSpace = 10
+		g := newDiskGuard(opts, nil)
+		g.free = func(string) (uint64, error) {
+			return 0, nil
+		}
+		g.start()
+		defer func() {
+			assert.NoError(t, g.Close())
+		}()
+
+		assert.False(t, g.allow(FatalLevel))
+	})
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package log
+
+import "errors"
+
+// diskFree is not supported on this platform.
+func diskFree(_ string) (uint64, error) {
+	return 0, errors.New("disk space check is not supported on this platform")
+}
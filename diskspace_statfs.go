@@ -0,0 +1,17 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package log
+
+import "syscall"
+
+// diskFree returns the available space in bytes of the filesystem
+// containing the given path.
+func diskFree(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	//nolint:unconvert
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
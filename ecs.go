@@ -0,0 +1,196 @@
+package log
+
+import (
+	"encoding/json"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// ECSVersion is the version of the Elastic Common Schema of FormatECS.
+const ECSVersion = "8.11.0"
+
+var _presetPool = buffer.NewPool()
+
+// ecsEncoder is a zapcore.Encoder which encodes the entries in the Elastic
+// Common Schema, e.g.
+//
+//	{"@timestamp":"2006-01-02T15:04:05.000Z","log.level":"error","message":"failed",
+//	"log.origin":{"file.name":"app/main.go","file.line":10,"function":"main.run"},
+//	"ecs.version":"8.11.0","error":{"message":"boom","type":"*errors.errorString"},
+//	"fields":{"user_id":1}}
+//
+// The fields are placed under the namespace, or at the top level if the
+// namespace is empty.
+type ecsEncoder struct {
+	// Encoder is the JSON encoder of the user fields
+	zapcore.Encoder
+	head      zapcore.Encoder
+	cfg       zapcore.EncoderConfig
+	namespace string
+}
+
+// NewECSEncoder creates an encoder which encodes the entries in the Elastic
+// Common Schema, the fields are placed under the namespace, or at the top
+// level if the namespace is empty. The time, level and caller are omitted if
+// the corresponding keys of the cfg are empty.
+func NewECSEncoder(cfg zapcore.EncoderConfig, namespace string) zapcore.Encoder {
+	headCfg := zapcore.EncoderConfig{
+		LevelKey:    "log.level",
+		NameKey:     "log.logger",
+		MessageKey:  "message",
+		EncodeLevel: zapcore.LowercaseLevelEncoder,
+		EncodeTime:  zapcore.RFC3339NanoTimeEncoder,
+	}
+	if cfg.TimeKey != "" {
+		headCfg.TimeKey = "@timestamp"
+	}
+	if cfg.LevelKey == "" {
+		headCfg.LevelKey = ""
+	}
+	return &ecsEncoder{
+		Encoder:   zapcore.NewJSONEncoder(fieldsEncoderConfig(cfg)),
+		head:      zapcore.NewJSONEncoder(headCfg),
+		cfg:       cfg,
+		namespace: namespace,
+	}
+}
+
+func (enc *ecsEncoder) Clone() zapcore.Encoder {
+	return &ecsEncoder{
+		Encoder:   enc.Encoder.Clone(),
+		head:      enc.head,
+		cfg:       enc.cfg,
+		namespace: enc.namespace,
+	}
+}
+
+func (enc *ecsEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	var (
+		extra []Field
+		err   error
+	)
+	user := make([]Field, 0, len(fields))
+	for i := range fields {
+		if err == nil {
+			if e, ok := errorOf(fields[i]); ok && e != nil {
+				err = e
+				continue
+			}
+		}
+		user = append(user, fields[i])
+	}
+	// merges the fields attached by WrapErr and WithErrFields like Err
+	if err != nil {
+		user = append(user, ErrFields(err)...)
+	}
+
+	if ent.Caller.Defined && enc.cfg.CallerKey != "" {
+		extra = append(extra, Object("log.origin", ecsOrigin(ent.Caller)))
+	}
+	extra = append(extra, String("ecs.version", ECSVersion))
+	if err != nil || ent.Stack != "" {
+		extra = append(extra, Object("error", ecsError{err: err, stack: ent.Stack}))
+	}
+
+	head := ent
+	head.Caller = zapcore.EntryCaller{}
+	head.Stack = ""
+	headBuf, encErr := enc.head.EncodeEntry(head, extra)
+	if encErr != nil {
+		return nil, encErr
+	}
+	defer headBuf.Free()
+	body, encErr := enc.Encoder.Clone().EncodeEntry(zapcore.Entry{}, user)
+	if encErr != nil {
+		return nil, encErr
+	}
+	defer body.Free()
+
+	return mergeJSON(headBuf, body, enc.namespace, enc.cfg.LineEnding), nil
+}
+
+func ecsOrigin(caller zapcore.EntryCaller) zapcore.ObjectMarshaler {
+	return zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		enc.AddString("file.name", callerFile(caller))
+		enc.AddInt("file.line", caller.Line)
+		if caller.Function != "" {
+			enc.AddString("function", caller.Function)
+		}
+		return nil
+	})
+}
+
+type ecsError struct {
+	err   error
+	stack string
+}
+
+func (e ecsError) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	stack := e.stack
+	if e.err != nil {
+		enc.AddString("message", e.err.Error())
+		enc.AddString("type", errorType(e.err))
+		if causes := errorCauses(e.err, false, 0); len(causes) > 0 {
+			_ = enc.AddArray("causes", causes)
+		}
+		// prefers the stacktrace of the error, where it was created
+		if s := errorStack(e.err); s != "" {
+			stack = s
+		}
+	}
+	if stack != "" {
+		enc.AddString("stack_trace", stack)
+	}
+	return nil
+}
+
+// callerFile returns the trimmed file path of the caller without the line,
+// e.g. log/ecs.go.
+func callerFile(caller zapcore.EntryCaller) string {
+	path := caller.TrimmedPath()
+	if i := strings.LastIndexByte(path, ':'); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
+
+// fieldsEncoderConfig returns the config of the JSON encoder which only
+// encodes the fields of the entries.
+func fieldsEncoderConfig(cfg zapcore.EncoderConfig) zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		LineEnding:     "\n",
+		EncodeTime:     cfg.EncodeTime,
+		EncodeDuration: cfg.EncodeDuration,
+	}
+}
+
+// mergeJSON merges the JSON object body into the JSON object head, under the
+// namespace or at the top level if the namespace is empty.
+func mergeJSON(head, body *buffer.Buffer, namespace, lineEnding string) *buffer.Buffer {
+	if lineEnding == "" {
+		lineEnding = zapcore.DefaultLineEnding
+	}
+	h := strings.TrimRight(head.String(), "\n")
+	b := strings.TrimRight(body.String(), "\n")
+
+	out := _presetPool.Get()
+	out.AppendString(strings.TrimSuffix(h, "}"))
+	if b != "{}" {
+		if len(h) > 2 {
+			out.AppendByte(',')
+		}
+		if namespace != "" {
+			key, _ := json.Marshal(namespace)
+			_, _ = out.Write(key)
+			out.AppendByte(':')
+			out.AppendString(b)
+		} else {
+			out.AppendString(b[1 : len(b)-1])
+		}
+	}
+	out.AppendByte('}')
+	out.AppendString(lineEnding)
+	return out
+}
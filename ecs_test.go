@@ -0,0 +1,72 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestECSEncoder(t *testing.T) {
+	cfg := zapcore.EncoderConfig{
+		TimeKey:   "time",
+		LevelKey:  "level",
+		CallerKey: "caller",
+	}
+	ent := zapcore.Entry{
+		Level:   ErrorLevel,
+		Time:    time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+		Message: "failed",
+		Caller:  zapcore.NewEntryCaller(0, "/src/app/main.go", 10, true),
+		Stack:   "main.run\n\t/src/app/main.go:10",
+	}
+
+	t.Run("with namespace", func(t *testing.T) {
+		enc := NewECSEncoder(cfg, "fields")
+		enc.AddString("service", "api")
+		buf, err := enc.EncodeEntry(ent, []Field{Err(errors.New("boom")), Int("user_id", 1)})
+		assert.NoError(t, err)
+
+		got := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+		assert.Equal(t, map[string]interface{}{
+			"@timestamp": "2006-01-02T15:04:05Z",
+			"log.level":  "error",
+			"message":    "failed",
+			"log.origin": map[string]interface{}{
+				"file.name": "app/main.go",
+				"file.line": float64(10),
+			},
+			"ecs.version": ECSVersion,
+			"error": map[string]interface{}{
+				"message":     "boom",
+				"type":        "*errors.errorString",
+				"stack_trace": "main.run\n\t/src/app/main.go:10",
+			},
+			"fields": map[string]interface{}{
+				"service": "api",
+				"user_id": float64(1),
+			},
+		}, got)
+	})
+
+	t.Run("error fields", func(t *testing.T) {
+		enc := NewECSEncoder(zapcore.EncoderConfig{}, "fields")
+		err := WrapErr(errors.New("not found"), "load user", String("user_id", "42"))
+		buf, encErr := enc.EncodeEntry(zapcore.Entry{Message: "failed"}, []Field{Err(err)})
+		assert.NoError(t, encErr)
+		assert.Equal(t, `{"message":"failed","ecs.version":"`+ECSVersion+`","error":{"message":"load user: not found",`+
+			`"type":"*errors.errorString","causes":[{"message":"not found","type":"*errors.errorString"}]},`+
+			`"fields":{"user_id":"42"}}`+"\n", buf.String())
+	})
+
+	t.Run("without namespace", func(t *testing.T) {
+		enc := NewECSEncoder(zapcore.EncoderConfig{LevelKey: "level"}, "")
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello"}, []Field{String("key", "value")})
+		assert.NoError(t, err)
+		assert.Equal(t, `{"log.level":"info","message":"hello","ecs.version":"`+ECSVersion+`","key":"value"}`+"\n", buf.String())
+	})
+}
@@ -8,100 +8,146 @@ import (
 	"time"
 
 	"go.uber.org/zap/zapcore"
-	lumberjack "gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Formats of the log encoders.
+const (
+	// FormatConsole is the plain-text format of zapcore.NewConsoleEncoder.
+	FormatConsole = "console"
+	// FormatJSON is the JSON format of zapcore.NewJSONEncoder.
+	FormatJSON = "json"
+	// FormatLogfmt is the key=value format of NewLogfmtEncoder.
+	FormatLogfmt = "logfmt"
+	// FormatECS is the Elastic Common Schema JSON format of NewECSEncoder.
+	FormatECS = "ecs"
+	// FormatGCP is the Google Cloud Logging structured JSON format of NewGCPEncoder.
+	FormatGCP = "gcp"
+	// FormatDev is the human-friendly format of NewDevEncoder.
+	FormatDev = "dev"
 )
 
 // FilenameEncoder log filename encoder,
-// return the full name of the log file.
+// return the full name of the log file. The built-in encoders, e.g.
+// DefaultFilenameEncoder, follow the Clock and the TimeZone of the Options.
 type FilenameEncoder func() string
 
+// TimeFilenameEncoder log filename encoder of the time,
+// return the full name of the log file. The time is the current time of
+// the Clock in the TimeZone of the Options.
+type TimeFilenameEncoder func(t time.Time) string
+
 // DefaultFilenameEncoder return <process name>-<date>.log.
 // Rotates daily based on date (YYYYMMDD format).
 func DefaultFilenameEncoder() string {
-	return fmt.Sprintf("%s-%s.log", filepath.Base(os.Args[0]), time.Now().Format("20060102"))
+	return DailyTimeFilenameEncoder(time.Now())
 }
 
 // HourlyFilenameEncoder returns <process name>-<date>-<hour>.log.
 // Rotates hourly based on date and hour (YYYYMMDD-HH format).
 func HourlyFilenameEncoder() string {
-	return fmt.Sprintf("%s-%s.log", filepath.Base(os.Args[0]), time.Now().Format("20060102-15"))
+	return HourlyTimeFilenameEncoder(time.Now())
 }
 
 // MinutelyFilenameEncoder returns <process name>-<date>-<hour>-<minute>.log.
 // Rotates every minute based on date, hour and minute (YYYYMMDD-HHMM format).
 func MinutelyFilenameEncoder() string {
-	return fmt.Sprintf("%s-%s.log", filepath.Base(os.Args[0]), time.Now().Format("20060102-1504"))
+	return MinutelyTimeFilenameEncoder(time.Now())
 }
 
-func DefaultTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
-	enc.AppendString(t.Format("2006-01-02 15:04:05.000"))
+// DailyTimeFilenameEncoder returns <process name>-<date>.log of the time,
+// it's the TimeFilenameEncoder of DefaultFilenameEncoder.
+func DailyTimeFilenameEncoder(t time.Time) string {
+	return timeFilename(dailyFilenameLayout, t)
 }
 
-func rollingFileEncoder(opts *Options, encoder FilenameEncoder) (zapcore.WriteSyncer, io.Closer, string) {
-	encoded := encoder()
-	f := filepath.Join(opts.Output, encoded)
-	if opts.DisableRotate {
-		fd, err := os.OpenFile(f, os.O_APPEND|os.O_CREATE|os.O_WRONLY|os.O_SYNC, 0o644)
-		if err != nil {
-			panic(err)
-		}
-		return zapcore.AddSync(fd), fd, f
-	}
+// HourlyTimeFilenameEncoder returns <process name>-<date>-<hour>.log of the
+// time, it's the TimeFilenameEncoder of HourlyFilenameEncoder.
+func HourlyTimeFilenameEncoder(t time.Time) string {
+	return timeFilename(hourlyFilenameLayout, t)
+}
 
-	// lumberjack.Logger is already safe for concurrent use, so we don't need to
-	// lock it.
-	jackl := &lumberjack.Logger{
-		Filename:   f,
-		MaxSize:    opts.MaxSize,
-		MaxAge:     opts.MaxAge,
-		MaxBackups: opts.MaxBackups,
-	}
+// MinutelyTimeFilenameEncoder returns <process name>-<date>-<hour>-<minute>.log
+// of the time, it's the TimeFilenameEncoder of MinutelyFilenameEncoder.
+func MinutelyTimeFilenameEncoder(t time.Time) string {
+	return timeFilename(minutelyFilenameLayout, t)
+}
 
-	// Wrap lumberjack with time-based rotation checker
-	writer := &timeBasedRotateWriter{
-		logger:          jackl,
-		opts:            opts,
-		encoder:         encoder,
-		currentFilename: f,
-	}
+// The time layouts of the built-in filename encoders.
+const (
+	dailyFilenameLayout    = "20060102"
+	hourlyFilenameLayout   = "20060102-15"
+	minutelyFilenameLayout = "20060102-1504"
+)
 
-	return zapcore.AddSync(writer), writer, f
+// timeFilename returns <process name>-<time>.log.
+func timeFilename(layout string, t time.Time) string {
+	return fmt.Sprintf("%s-%s.log", filepath.Base(os.Args[0]), t.Format(layout))
 }
 
-// timeBasedRotateWriter wraps lumberjack.Logger to support time-based rotation
-// It automatically detects the rotation interval based on the filename pattern
-// generated by FilenameEncoder
-type timeBasedRotateWriter struct {
-	logger          *lumberjack.Logger
-	opts            *Options
-	encoder         FilenameEncoder
-	currentFilename string
+func DefaultTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(t.Format("2006-01-02 15:04:05.000"))
 }
 
-func (w *timeBasedRotateWriter) Write(p []byte) (n int, err error) {
-	// Get the current filename from encoder
-	encoded := w.encoder()
-	newFilename := filepath.Join(w.opts.Output, encoded)
-
-	// Check if filename has changed (indicates time period changed)
-	if newFilename != w.currentFilename {
-		// Filename changed, need to rotate
-		// Update the logger's filename and rotate
-		w.logger.Filename = newFilename
-		if err := w.logger.Rotate(); err != nil {
-			// Log error but continue
-			fmt.Fprintf(os.Stderr, "failed to rotate log file: %v\n", err)
-		}
-		w.currentFilename = newFilename
+// The named encoders of Options.TimeFormat, Options.LevelFormat,
+// Options.CallerFormat and Options.DurationFormat.
+var (
+	timeEncoders = map[string]TimeEncoder{
+		"default":      DefaultTimeEncoder,
+		"rfc3339":      zapcore.RFC3339TimeEncoder,
+		"rfc3339nano":  zapcore.RFC3339NanoTimeEncoder,
+		"iso8601":      zapcore.ISO8601TimeEncoder,
+		"epoch":        zapcore.EpochTimeEncoder,
+		"epoch-millis": zapcore.EpochMillisTimeEncoder,
+		"epoch-nanos":  zapcore.EpochNanosTimeEncoder,
 	}
+	levelEncoders = map[string]LevelEncoder{
+		"capital":         zapcore.CapitalLevelEncoder,
+		"capital-color":   zapcore.CapitalColorLevelEncoder,
+		"lowercase":       zapcore.LowercaseLevelEncoder,
+		"lowercase-color": zapcore.LowercaseColorLevelEncoder,
+	}
+	callerEncoders = map[string]CallerEncoder{
+		"short":     zapcore.ShortCallerEncoder,
+		"full-path": zapcore.FullCallerEncoder,
+	}
+	durationEncoders = map[string]zapcore.DurationEncoder{
+		"string":  zapcore.StringDurationEncoder,
+		"seconds": zapcore.SecondsDurationEncoder,
+		"millis":  zapcore.MillisDurationEncoder,
+		"nanos":   zapcore.NanosDurationEncoder,
+	}
+)
 
-	return w.logger.Write(p)
-}
-
-func (w *timeBasedRotateWriter) Sync() error {
-	return w.logger.Close()
+// newEncoder creates an encoder of the given format, defaults to FormatConsole.
+// The color is only used by FormatDev.
+func newEncoder(opts *Options, format string, cfg zapcore.EncoderConfig, color bool) zapcore.Encoder {
+	switch format {
+	case FormatJSON:
+		return zapcore.NewJSONEncoder(cfg)
+	case FormatLogfmt:
+		return NewLogfmtEncoder(cfg)
+	case FormatECS:
+		return NewECSEncoder(cfg, opts.ECSNamespace)
+	case FormatGCP:
+		return NewGCPEncoder(cfg, opts.GCPProjectID, opts.GCPSplitTimestamp)
+	case FormatDev:
+		return NewDevEncoder(cfg, DevEncoderConfig{
+			Color:           color,
+			RelativeTime:    opts.ConsoleRelativeTime,
+			MultilineFields: opts.ConsoleMultilineFields,
+			Hyperlinks:      opts.ConsoleHyperlinks,
+			Start:           opts.clock().Now(),
+		})
+	default:
+		return zapcore.NewConsoleEncoder(cfg)
+	}
 }
 
-func (w *timeBasedRotateWriter) Close() error {
-	return w.logger.Close()
+func rollingFileEncoder(opts *Options, encoder FilenameEncoder, header func() []byte) (zapcore.WriteSyncer, io.Closer, string, error) {
+	f := filepath.Join(opts.Output, encoder())
+	writer, err := writers.acquire(f, opts, encoder, header)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return writer, writer, f, nil
 }
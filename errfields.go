@@ -0,0 +1,122 @@
+package log
+
+// fieldsError is an error carrying the fields of the context where it
+// happened, the fields are merged into the entry when it is logged by Err.
+type fieldsError struct {
+	err    error
+	msg    string
+	fields []Field
+}
+
+// WrapErr wraps the error with the message and attaches the fields to it,
+// the message of the returned error is "msg: err". If the error is nil,
+// WrapErr returns nil.
+//
+// The fields of the error chain are merged into the entry when the error is
+// logged by Err or NamedErr, e.g.
+//
+//	if err := load(id); err != nil {
+//		return log.WrapErr(err, "load user", log.String("user_id", id))
+//	}
+func WrapErr(err error, msg string, fields ...Field) error {
+	if err == nil {
+		return nil
+	}
+	return &fieldsError{err: err, msg: msg, fields: fields}
+}
+
+// WithErrFields attaches the fields to the error without changing its
+// message. If the error is nil, WithErrFields returns nil.
+func WithErrFields(err error, fields ...Field) error {
+	if err == nil {
+		return nil
+	}
+	return &fieldsError{err: err, fields: fields}
+}
+
+// ErrFields returns the fields attached to the error chain, the fields of the
+// inner errors come first. If a key is attached more than once, the value of
+// the outermost error is kept at the position of the innermost.
+func ErrFields(err error) []Field {
+	var chain []*fieldsError
+	walkErrors(err, 0, func(e error) {
+		if fe, ok := e.(*fieldsError); ok && len(fe.fields) > 0 {
+			chain = append(chain, fe)
+		}
+	})
+	if len(chain) == 0 {
+		return nil
+	}
+
+	var (
+		fields []Field
+		index  = map[string]int{}
+	)
+	for _, fe := range chain {
+		for _, f := range fe.fields {
+			if j, ok := index[f.Key]; ok && f.Key != "" {
+				fields[j] = f
+				continue
+			}
+			index[f.Key] = len(fields)
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func (e *fieldsError) Error() string {
+	if e.msg == "" {
+		return e.err.Error()
+	}
+	return e.msg + ": " + e.err.Error()
+}
+
+func (e *fieldsError) Unwrap() error {
+	return e.err
+}
+
+// isTransparent reports whether the error only carries the fields, it is
+// omitted from the causes.
+func isTransparent(err error) bool {
+	fe, ok := err.(*fieldsError)
+	return ok && fe.msg == ""
+}
+
+// skipTransparent returns the first error of the chain which is not
+// transparent.
+func skipTransparent(err error) error {
+	for depth := 0; isTransparent(err) && depth < maxErrorDepth; depth++ {
+		err = unwrapSingle(err)
+	}
+	return err
+}
+
+// skipFieldsErrors returns the first error of the chain which is not created
+// by WrapErr or WithErrFields.
+func skipFieldsErrors(err error) error {
+	for depth := 0; depth < maxErrorDepth; depth++ {
+		fe, ok := err.(*fieldsError)
+		if !ok {
+			break
+		}
+		err = fe.err
+	}
+	return err
+}
+
+// walkErrors calls the fn with the errors of the tree, the inner errors
+// first.
+func walkErrors(err error, depth int, fn func(error)) {
+	if err == nil || depth >= maxErrorDepth {
+		return
+	}
+	if errs, ok := unwrapJoined(err); ok {
+		for _, e := range errs {
+			walkErrors(e, depth+1, fn)
+		}
+	} else {
+		walkErrors(unwrapSingle(err), depth+1, fn)
+	}
+	fn(err)
+}
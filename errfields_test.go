@@ -0,0 +1,107 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapErr(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		assert.Nil(t, WrapErr(nil, "load user", String("user_id", "42")))
+		assert.Nil(t, WithErrFields(nil, String("user_id", "42")))
+		assert.Nil(t, ErrFields(nil))
+		assert.Nil(t, ErrFields(errors.New("plain")))
+	})
+
+	t.Run("unwrap", func(t *testing.T) {
+		base := errors.New("not found")
+		err := WrapErr(base, "load user", String("user_id", "42"))
+		assert.Equal(t, "load user: not found", err.Error())
+		assert.True(t, errors.Is(err, base))
+
+		err = WithErrFields(base, String("user_id", "42"))
+		assert.Equal(t, "not found", err.Error())
+		assert.True(t, errors.Is(err, base))
+	})
+
+	t.Run("fields", func(t *testing.T) {
+		err := WrapErr(errors.New("not found"), "query", String("table", "users"), Int("attempt", 1))
+		err = fmt.Errorf("handler: %w", err)
+		err = WrapErr(err, "load user", String("user_id", "42"), Int("attempt", 3))
+
+		assert.Equal(t, []Field{
+			String("table", "users"),
+			Int("attempt", 3),
+			String("user_id", "42"),
+		}, ErrFields(err))
+	})
+
+	t.Run("joined", func(t *testing.T) {
+		err := testJoinError{
+			WithErrFields(errors.New("a"), String("a", "1")),
+			WithErrFields(errors.New("b"), String("b", "2")),
+		}
+		assert.Equal(t, []Field{String("a", "1"), String("b", "2")}, ErrFields(err))
+	})
+
+	t.Run("encode", func(t *testing.T) {
+		err := WithErrFields(errors.New("not found"), String("table", "users"))
+		err = WrapErr(err, "load user", String("user_id", "42"))
+
+		got := encodeFields(t, Err(err))
+		assert.Equal(t, map[string]interface{}{
+			"error":     "load user: not found",
+			"errorType": "*errors.errorString",
+			"errorCauses": []interface{}{
+				map[string]interface{}{
+					"message": "not found",
+					"type":    "*errors.errorString",
+				},
+			},
+			"table":   "users",
+			"user_id": "42",
+		}, got)
+
+		// the wrapped errors report the type of the error they wrap
+		got = encodeFields(t, Err(fmt.Errorf("handler: %w", WrapErr(errors.New("not found"), "load user"))))
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{
+				"message": "load user: not found",
+				"type":    "*errors.errorString",
+			},
+			map[string]interface{}{
+				"message": "not found",
+				"type":    "*errors.errorString",
+			},
+		}, got["errorCauses"])
+
+		got = encodeFields(t, Err(WithErrFields(errors.New("timeout"), Int("attempt", 2))))
+		assert.Equal(t, map[string]interface{}{
+			"error":   "timeout",
+			"attempt": float64(2),
+		}, got)
+	})
+}
+
+func TestSugaredErrFields(t *testing.T) {
+	l := newContextTestLogger(t)
+	err := WrapErr(errors.New("not found"), "load user", String("user_id", "42"))
+	l.Error("failed", "err", err, "attempt", 2)
+	l.ErrorContext(context.Background(), "failed", String("op", "load"), "err", err)
+
+	lines := readContextTestLogger(t, l)
+	assert.Equal(t, 2, len(lines))
+	assert.Contains(t, lines[0], `"msg":"failed","err":"load user: not found","errType":"*errors.errorString",`)
+	assert.Contains(t, lines[0], `"user_id":"42","attempt":2}`)
+	assert.Contains(t, lines[1], `"op":"load","err":"load user: not found"`)
+	assert.Contains(t, lines[1], `"user_id":"42"`)
+
+	args := []interface{}{"key", "value", String("f", "v"), "n", 1}
+	assert.Equal(t, args, errorArgs(args))
+	assert.Equal(t, []interface{}{String("f", "v"), NamedErr("err", err), "dangling"},
+		errorArgs([]interface{}{String("f", "v"), "err", err, "dangling"}))
+}
@@ -0,0 +1,304 @@
+package log
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// maxErrorDepth limits the causes of an error, in case of cyclic chains.
+const maxErrorDepth = 32
+
+// Err creates a field which encodes the error under the key error, e.g.
+//
+//	{"error":"read config: open app.yaml: no such file or directory",
+//	"errorType":"*fmt.wrapError",
+//	"errorCauses":[{"message":"open app.yaml: no such file or directory","type":"*fs.PathError"},
+//	{"message":"no such file or directory","type":"syscall.Errno"}]}
+//
+// See NamedErr for the details.
+func Err(err error) Field {
+	return NamedErr("error", err)
+}
+
+// NamedErr creates a field which encodes the error under the key and the
+// stacktrace under the key + "Stack". If the error has causes, the type of
+// the error is encoded under the key + "Type" and the causes under the
+// key + "Causes", so a plain error is encoded as by zap.Error.
+//
+// The causes are walked by the Unwrap() error and Unwrap() []error methods,
+// the chain of Unwrap() error is flattened, the errors of Unwrap() []error
+// are nested under their causes key. The stacktrace is extracted from the
+// innermost error implementing StackTrace() or Stack(), or printing it with
+// %+v, e.g. the errors of github.com/pkg/errors. If the error is nil, the
+// field is a no-op.
+func NamedErr(key string, err error) Field {
+	if err == nil {
+		return Field{Type: zapcore.SkipType}
+	}
+	return Field{Type: zapcore.InlineMarshalerType, Interface: errorField{key: key, err: err}}
+}
+
+// errorArgs returns the keysAndValues of the sugared logger with the error
+// values replaced by the NamedErr fields of their keys, as logr and slog
+// do, so that the fields of WrapErr are merged. The keysAndValues are
+// copied only if they have an error value.
+func errorArgs(keysAndValues []interface{}) []interface{} {
+	var args []interface{}
+	for i := 0; i < len(keysAndValues); i++ {
+		if _, ok := keysAndValues[i].(Field); ok || i+1 == len(keysAndValues) {
+			if args != nil {
+				args = append(args, keysAndValues[i])
+			}
+			continue
+		}
+		key, ok := keysAndValues[i].(string)
+		err, isErr := keysAndValues[i+1].(error)
+		if !ok || !isErr {
+			if args != nil {
+				args = append(args, keysAndValues[i], keysAndValues[i+1])
+			}
+			i++
+			continue
+		}
+		if args == nil {
+			args = append(make([]interface{}, 0, len(keysAndValues)), keysAndValues[:i]...)
+		}
+		args = append(args, NamedErr(key, err))
+		i++
+	}
+	if args == nil {
+		return keysAndValues
+	}
+	return args
+}
+
+// Errors creates a field which encodes the errors as an array of the
+// objects which encode the error under the key error like Err.
+func Errors(key string, errs []error) Field {
+	return Array(key, errorArray(errs))
+}
+
+// errorOf returns the error of the field created by Err, NamedErr or
+// zap.Error.
+func errorOf(f Field) (error, bool) {
+	switch f.Type {
+	case zapcore.ErrorType:
+		err, ok := f.Interface.(error)
+		return err, ok
+	case zapcore.InlineMarshalerType:
+		if ef, ok := f.Interface.(errorField); ok {
+			return ef.err, true
+		}
+	}
+	return nil, false
+}
+
+type errorField struct {
+	key string
+	err error
+}
+
+func (e errorField) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString(e.key, e.err.Error())
+	if causes := errorCauses(e.err, false, 0); len(causes) > 0 {
+		enc.AddString(e.key+"Type", errorType(e.err))
+		_ = enc.AddArray(e.key+"Causes", causes)
+	}
+	if stack := errorStack(e.err); stack != "" {
+		enc.AddString(e.key+"Stack", stack)
+	}
+	// merges the fields attached by WrapErr and WithErrFields
+	for _, f := range ErrFields(e.err) {
+		f.AddTo(enc)
+	}
+	return nil
+}
+
+type errorArray []error
+
+func (errs errorArray) MarshalLogArray(arr zapcore.ArrayEncoder) error {
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		_ = arr.AppendObject(errorField{key: "error", err: err})
+	}
+	return nil
+}
+
+// errorObject encodes the cause as an object of message, type and causes.
+type errorObject struct {
+	err error
+	// inChain is true if the error is in the flattened chain of the parent,
+	// its causes are encoded by the parent
+	inChain bool
+	depth   int
+}
+
+func (e errorObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("message", e.err.Error())
+	enc.AddString("type", errorType(e.err))
+	if causes := errorCauses(e.err, e.inChain, e.depth); len(causes) > 0 {
+		_ = enc.AddArray("causes", causes)
+	}
+	return nil
+}
+
+type errorObjects []errorObject
+
+func (objs errorObjects) MarshalLogArray(arr zapcore.ArrayEncoder) error {
+	for _, obj := range objs {
+		_ = arr.AppendObject(obj)
+	}
+	return nil
+}
+
+// errorCauses returns the causes of the error. The causes of a joined error
+// are its errors, the causes of a wrapped error are the flattened chain,
+// which ends at a joined error. An error in the chain of the parent has no
+// causes unless it is a joined error.
+func errorCauses(err error, inChain bool, depth int) errorObjects {
+	if depth >= maxErrorDepth {
+		return nil
+	}
+	err = skipTransparent(err)
+	if errs, ok := unwrapJoined(err); ok {
+		causes := make(errorObjects, 0, len(errs))
+		for _, e := range errs {
+			if e != nil {
+				causes = append(causes, errorObject{err: e, depth: depth + 1})
+			}
+		}
+		return causes
+	}
+	if inChain {
+		return nil
+	}
+	var causes errorObjects
+	for cause := unwrapSingle(err); cause != nil && len(causes) < maxErrorDepth; cause = unwrapSingle(cause) {
+		if isTransparent(cause) {
+			continue
+		}
+		causes = append(causes, errorObject{err: cause, inChain: true, depth: depth + 1})
+		if _, ok := unwrapJoined(cause); ok {
+			break
+		}
+	}
+	return causes
+}
+
+func unwrapSingle(err error) error {
+	if u, ok := err.(interface{ Unwrap() error }); ok {
+		return u.Unwrap()
+	}
+	return nil
+}
+
+func unwrapJoined(err error) ([]error, bool) {
+	if u, ok := err.(interface{ Unwrap() []error }); ok {
+		return u.Unwrap(), true
+	}
+	return nil, false
+}
+
+// errorType returns the type of the error, the type of the wrapped error is
+// returned for the errors of WrapErr and WithErrFields.
+func errorType(err error) string {
+	return fmt.Sprintf("%T", skipFieldsErrors(err))
+}
+
+// errorStack returns the stacktrace of the innermost error in the chain
+// which has one, in the format of zap. The stacktraces printed by the
+// fmt.Formatter of the errors are only looked up if no error in the chain
+// returns one by a method, innermost first, until an error prints one.
+func errorStack(err error) string {
+	var chain []error
+	for depth := 0; err != nil && depth < maxErrorDepth; depth++ {
+		chain = append(chain, err)
+		err = unwrapSingle(err)
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		if stack := stackOf(chain[i]); stack != "" {
+			return stack
+		}
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		if f, ok := chain[i].(fmt.Formatter); ok {
+			if stack := formattedStack(f); stack != "" {
+				return stack
+			}
+		}
+	}
+	return ""
+}
+
+// stackOf returns the stacktrace of the error if it implements a
+// StackTrace() or Stack() method, which returns the program counters, a
+// string or a []byte.
+func stackOf(err error) string {
+	switch e := err.(type) {
+	case interface{ StackTrace() []uintptr }:
+		return formatFrames(e.StackTrace())
+	case interface{ Stack() []uintptr }:
+		return formatFrames(e.Stack())
+	case interface{ StackTrace() string }:
+		return e.StackTrace()
+	case interface{ Stack() string }:
+		return e.Stack()
+	case interface{ Stack() []byte }:
+		return string(e.Stack())
+	}
+	return ""
+}
+
+// formattedStack returns the trailing frames printed by the %+v verb of the
+// error, e.g. the errors of github.com/pkg/errors. The frames are in the
+// format of zap, a function line followed by a tab-prefixed file:line line,
+// the output of the other errors is ignored.
+func formattedStack(f fmt.Formatter) string {
+	lines := strings.Split(fmt.Sprintf("%+v", f), "\n")
+	// the first line is the message
+	i := len(lines)
+	for i >= 3 && isFrameLocation(lines[i-1]) &&
+		lines[i-2] != "" && !strings.HasPrefix(lines[i-2], "\t") {
+		i -= 2
+	}
+	return strings.Join(lines[i:], "\n")
+}
+
+// isFrameLocation reports whether the line is the tab-prefixed file:line of
+// a frame.
+func isFrameLocation(line string) bool {
+	if !strings.HasPrefix(line, "\t") {
+		return false
+	}
+	colon := strings.LastIndexByte(line, ':')
+	if colon < 0 || colon == len(line)-1 {
+		return false
+	}
+	for _, c := range line[colon+1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// formatFrames formats the program counters returned by runtime.Callers in
+// the format of zap.
+func formatFrames(pcs []uintptr) string {
+	var sb strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for more := len(pcs) > 0; more; {
+		var frame runtime.Frame
+		frame, more = frames.Next()
+		if sb.Len() > 0 {
+			sb.WriteByte('\n')
+		}
+		fmt.Fprintf(&sb, "%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+	}
+	return sb.String()
+}
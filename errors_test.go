@@ -0,0 +1,193 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+type testJoinError []error
+
+func (e testJoinError) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e testJoinError) Unwrap() []error { return e }
+
+type testFrame uintptr
+
+type testStackTrace []testFrame
+
+// testStackError has a stacktrace like the errors of github.com/pkg/errors.
+type testStackError struct {
+	msg   string
+	stack testStackTrace
+}
+
+func newTestStackError(msg string) error {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	stack := make(testStackTrace, n)
+	for i := range stack {
+		stack[i] = testFrame(pcs[i])
+	}
+	return &testStackError{msg: msg, stack: stack}
+}
+
+func (e *testStackError) Error() string { return e.msg }
+
+func (e *testStackError) StackTrace() testStackTrace { return e.stack }
+
+// Format prints the stacktrace with %+v like the errors of github.com/pkg/errors.
+func (e *testStackError) Format(s fmt.State, verb rune) {
+	_, _ = io.WriteString(s, e.msg)
+	if verb != 'v' || !s.Flag('+') {
+		return
+	}
+	for _, pc := range e.stack {
+		fn := runtime.FuncForPC(uintptr(pc) - 1)
+		file, line := fn.FileLine(uintptr(pc) - 1)
+		_, _ = fmt.Fprintf(s, "\n%s\n\t%s:%d", fn.Name(), file, line)
+	}
+}
+
+type testStringStackError struct{}
+
+func (testStringStackError) Error() string { return "string stack" }
+
+func (testStringStackError) Stack() string { return "main.run\n\t/src/app/main.go:10" }
+
+type testFormatError struct{}
+
+func (testFormatError) Error() string { return "format" }
+
+func (testFormatError) Format(s fmt.State, _ rune) { _, _ = io.WriteString(s, "format\n\tdetails") }
+
+func encodeFields(t *testing.T, fields ...Field) map[string]interface{} {
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{})
+	buf, err := enc.EncodeEntry(zapcore.Entry{}, fields)
+	assert.NoError(t, err)
+	got := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	return got
+}
+
+func TestErr(t *testing.T) {
+	t.Run("chain", func(t *testing.T) {
+		base := errors.New("no such file")
+		joined := testJoinError{base, fmt.Errorf("retry: %w", errors.New("timeout"))}
+		err := fmt.Errorf("read config: %w", fmt.Errorf("open: %w", joined))
+
+		got := encodeFields(t, Err(err))
+		assert.Equal(t, map[string]interface{}{
+			"error":     "read config: open: no such file\nretry: timeout",
+			"errorType": "*fmt.wrapError",
+			"errorCauses": []interface{}{
+				map[string]interface{}{
+					"message": "open: no such file\nretry: timeout",
+					"type":    "*fmt.wrapError",
+				},
+				map[string]interface{}{
+					"message": "no such file\nretry: timeout",
+					"type":    "log.testJoinError",
+					"causes": []interface{}{
+						map[string]interface{}{
+							"message": "no such file",
+							"type":    "*errors.errorString",
+						},
+						map[string]interface{}{
+							"message": "retry: timeout",
+							"type":    "*fmt.wrapError",
+							"causes": []interface{}{
+								map[string]interface{}{
+									"message": "timeout",
+									"type":    "*errors.errorString",
+								},
+							},
+						},
+					},
+				},
+			},
+		}, got)
+	})
+
+	t.Run("stack", func(t *testing.T) {
+		err := fmt.Errorf("wrapped: %w", newTestStackError("boom"))
+		got := encodeFields(t, NamedErr("cause", err))
+		assert.Equal(t, "wrapped: boom", got["cause"])
+		stack := got["causeStack"].(string)
+		assert.True(t, strings.HasPrefix(stack, "github.com/shipengqi/log.TestErr.func2\n\t"), stack)
+		assert.Contains(t, stack, "log/errors_test.go:")
+
+		got = encodeFields(t, Err(testStringStackError{}))
+		assert.Equal(t, "main.run\n\t/src/app/main.go:10", got["errorStack"])
+
+		// the errors without a stacktrace
+		assert.Equal(t, "", formattedStack(testFormatError{}))
+		got = encodeFields(t, Err(testFormatError{}))
+		assert.Equal(t, map[string]interface{}{"error": "format"}, got)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		got := encodeFields(t, Errors("errs", []error{errors.New("a"), nil, testStringStackError{}}), Err(nil))
+		assert.Equal(t, map[string]interface{}{
+			"errs": []interface{}{
+				map[string]interface{}{"error": "a"},
+				map[string]interface{}{
+					"error":      "string stack",
+					"errorStack": "main.run\n\t/src/app/main.go:10",
+				},
+			},
+		}, got)
+	})
+}
+
+// testCountFormatError wraps an error and counts the calls of Format.
+type testCountFormatError struct {
+	err   error
+	count *int
+}
+
+func (e testCountFormatError) Error() string { return "wrap: " + e.err.Error() }
+
+func (e testCountFormatError) Unwrap() error { return e.err }
+
+func (e testCountFormatError) Format(s fmt.State, _ rune) {
+	*e.count++
+	_, _ = fmt.Fprintf(s, "%+v\nwrap", e.err)
+}
+
+func TestErrorStackInnermost(t *testing.T) {
+	var count int
+	inner := newTestStackError("inner")
+	err := error(testCountFormatError{err: fmt.Errorf("outer: %w", testStringStackError{}), count: &count})
+	for i := 0; i < 8; i++ {
+		err = testCountFormatError{err: err, count: &count}
+	}
+	// the stack of a method is found without formatting the errors
+	assert.Equal(t, "main.run\n\t/src/app/main.go:10", errorStack(err))
+	assert.Equal(t, 0, count)
+
+	// the innermost formatted stack, each error is formatted at most once
+	err = inner
+	for i := 0; i < 8; i++ {
+		err = testCountFormatError{err: err, count: &count}
+	}
+	assert.Equal(t, formattedStack(inner.(fmt.Formatter)), errorStack(err))
+	assert.Equal(t, 0, count)
+	assert.True(t, strings.HasPrefix(errorStack(err), "github.com/shipengqi/log.TestErrorStackInnermost\n\t"))
+
+	// the formatted output without the frames is ignored
+	assert.Equal(t, "", formattedStack(testCountFormatError{err: errors.New("a\n\tb:c"), count: &count}))
+}
@@ -0,0 +1,230 @@
+package log
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultFileMode is the permission of the log files when FileMode is not set.
+	DefaultFileMode os.FileMode = 0o644
+	// DefaultDirMode is the permission of the created log directories when DirMode is not set.
+	DefaultDirMode os.FileMode = 0o755
+)
+
+// openFile opens the named log file with the given flag, the missing parent
+// directories and the file are created with the configured permissions
+// and ownership. The FileMode and the owner are also applied to an existing
+// file if they are set, so that the active file follows them before it's
+// rotated.
+func openFile(opts *Options, name string, flag int) (*os.File, error) {
+	if err := mkdirAll(opts, filepath.Dir(name)); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(name)
+	created := os.IsNotExist(err)
+
+	f, err := os.OpenFile(name, flag, opts.fileMode())
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case created:
+		// the permission passed to OpenFile is masked by umask
+		err = setFileOwnership(opts, f.Name(), opts.fileMode())
+	case info != nil:
+		err = updateFileOwnership(opts, f.Name(), info.Mode().Perm())
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// updateFileOwnership sets the permission of the existing file if FileMode
+// is set and differs from its mode, and the owner if it's set.
+func updateFileOwnership(opts *Options, name string, mode os.FileMode) error {
+	if opts.FileMode != 0 && mode != opts.fileMode() {
+		if err := os.Chmod(name, opts.fileMode()); err != nil {
+			return fmt.Errorf("can't change log file mode: %s", err)
+		}
+	}
+	return chownFile(opts, name)
+}
+
+// mkdirAll creates the directory and the missing parents with the
+// configured permissions and ownership.
+func mkdirAll(opts *Options, dir string) error {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil || !os.IsNotExist(err) {
+			break
+		}
+		missing = append(missing, d)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dir, opts.dirMode()); err != nil {
+		return fmt.Errorf("can't make directories for log file: %s", err)
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := setFileOwnership(opts, missing[i], opts.dirMode()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setFileOwnership sets the permission and the configured owner of the named file.
+func setFileOwnership(opts *Options, name string, mode os.FileMode) error {
+	if err := os.Chmod(name, mode); err != nil {
+		return fmt.Errorf("can't change log file mode: %s", err)
+	}
+	return chownFile(opts, name)
+}
+
+// chownFile sets the configured owner of the named file.
+func chownFile(opts *Options, name string) error {
+	if opts.FileUID == nil && opts.FileGID == nil {
+		return nil
+	}
+	uid, gid := -1, -1
+	if opts.FileUID != nil {
+		uid = *opts.FileUID
+	}
+	if opts.FileGID != nil {
+		gid = *opts.FileGID
+	}
+	if err := os.Chown(name, uid, gid); err != nil {
+		return fmt.Errorf("can't change log file owner: %s", err)
+	}
+	return nil
+}
+
+// compressFile compresses the given file to dst with gzip, and removes the
+// source file if successful.
+func compressFile(opts *Options, src, dst string) (err error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %s", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	gzf, err := openFile(opts, dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY)
+	if err != nil {
+		return fmt.Errorf("failed to open compressed log file: %s", err)
+	}
+	defer func() {
+		_ = gzf.Close()
+		if err != nil {
+			_ = os.Remove(dst)
+		}
+	}()
+
+	gz := gzip.NewWriter(gzf)
+	if _, err = io.Copy(gz, f); err != nil {
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	if err = gzf.Close(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	// keep the modification time, the retention sorts the backups by it
+	if info, err := os.Stat(src); err == nil {
+		_ = os.Chtimes(dst, info.ModTime(), info.ModTime())
+	}
+	return os.Remove(src)
+}
+
+// FileMode is the permission of the log files and directories. It's
+// encoded in JSON as an octal string, e.g. "0644", and decoded from an octal
+// string or a number, it's also a pflag.Value in octal.
+type FileMode os.FileMode
+
+func (m FileMode) String() string {
+	return fmt.Sprintf("%#o", os.FileMode(m))
+}
+
+func (m *FileMode) Set(s string) error {
+	mode, err := strconv.ParseUint(strings.TrimPrefix(s, "0o"), 8, 32)
+	if err != nil {
+		return fmt.Errorf("invalid file mode %q: %s", s, err)
+	}
+	*m = FileMode(mode)
+	return nil
+}
+
+func (m *FileMode) Type() string {
+	return "filemode"
+}
+
+func (m FileMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *FileMode) UnmarshalText(text []byte) error {
+	return m.Set(string(text))
+}
+
+// UnmarshalJSON decodes the octal string or the number of the mode.
+func (m *FileMode) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		return m.Set(s)
+	}
+	var mode uint32
+	if err := json.Unmarshal(data, &mode); err != nil {
+		return err
+	}
+	*m = FileMode(mode)
+	return nil
+}
+
+// optionalIntValue is a pflag.Value of an optional int, the int is nil
+// until the flag is set.
+type optionalIntValue struct {
+	p **int
+}
+
+func newOptionalIntValue(p **int) *optionalIntValue {
+	return &optionalIntValue{p: p}
+}
+
+func (v *optionalIntValue) String() string {
+	if v.p == nil || *v.p == nil {
+		return ""
+	}
+	return strconv.Itoa(**v.p)
+}
+
+func (v *optionalIntValue) Set(s string) error {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	*v.p = &i
+	return nil
+}
+
+func (v *optionalIntValue) Type() string {
+	return "int"
+}
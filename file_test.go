@@ -0,0 +1,148 @@
+package log
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilePermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions are not supported on windows")
+	}
+
+	t.Run("create nested directories", func(t *testing.T) {
+		dir := t.TempDir()
+		opts := NewOptions()
+		opts.DisableConsole = true
+		opts.DisableFile = false
+		opts.FileMode = 0o640
+		opts.DirMode = 0o750
+		opts.Output = dir
+		opts.FilenameEncoder = func() string {
+			return filepath.Join("a", "b", "test.log")
+		}
+		l := New(opts)
+		l.Info("Hello, world!")
+		assert.NoError(t, l.Close())
+
+		info, err := os.Stat(filepath.Join(dir, "a", "b", "test.log"))
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+		for _, v := range []string{"a", filepath.Join("a", "b")} {
+			info, err = os.Stat(filepath.Join(dir, v))
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o750), info.Mode().Perm())
+		}
+	})
+
+	t.Run("compress rotated files", func(t *testing.T) {
+		dir := t.TempDir()
+		name := "test-20060102.log"
+		rotated := make(chan string, 1)
+		opts := NewOptions()
+		opts.DisableConsole = true
+		opts.DisableFile = false
+		opts.DisableRotate = false
+		opts.Compress = true
+		opts.FileMode = 0o600
+		opts.Output = dir
+		opts.FilenameEncoder = func() string {
+			return name
+		}
+		opts.OnRotate = func(oldname, _ string) {
+			rotated <- oldname
+		}
+		l := New(opts)
+		l.Info("first")
+		name = "test-20060103.log"
+		l.Info("second")
+		assert.NoError(t, l.Close())
+
+		compressed := filepath.Join(dir, "test-20060102.log.gz")
+		assert.Equal(t, compressed, <-rotated)
+		assert.NoFileExists(t, filepath.Join(dir, "test-20060102.log"))
+		for _, v := range []string{compressed, filepath.Join(dir, name)} {
+			info, err := os.Stat(v)
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+		}
+	})
+}
+
+func TestFileModeFlag(t *testing.T) {
+	opts := NewOptions()
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	opts.AddFlags(fs)
+
+	assert.Nil(t, opts.FileUID)
+	assert.Nil(t, opts.FileGID)
+	assert.NoError(t, fs.Parse([]string{"--log.file-mode=0600", "--log.dir-mode=700",
+		"--log.file-uid=1000", "--log.file-gid=0"}))
+	assert.Equal(t, FileMode(0o600), opts.FileMode)
+	assert.Equal(t, FileMode(0o700), opts.DirMode)
+	assert.Equal(t, 1000, *opts.FileUID)
+	assert.Equal(t, 0, *opts.FileGID)
+	assert.Error(t, fs.Parse([]string{"--log.file-mode=0800"}))
+	assert.Error(t, fs.Parse([]string{"--log.file-uid=root"}))
+}
+
+func TestFileModeJSON(t *testing.T) {
+	opts := NewOptions()
+	assert.NoError(t, json.Unmarshal([]byte(`{"file-mode":"0640","dir-mode":"0o750"}`), opts))
+	assert.Equal(t, FileMode(0o640), opts.FileMode)
+	assert.Equal(t, FileMode(0o750), opts.DirMode)
+	assert.Contains(t, opts.String(), `"file-mode":"0640","dir-mode":"0750"`)
+
+	// a number is accepted as is
+	assert.NoError(t, json.Unmarshal([]byte(`{"file-mode":420}`), opts))
+	assert.Equal(t, FileMode(0o644), opts.FileMode)
+	assert.Error(t, json.Unmarshal([]byte(`{"file-mode":"rw-r--r--"}`), opts))
+}
+
+func TestExistingFilePermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions are not supported on windows")
+	}
+
+	for _, rotate := range []bool{false, true} {
+		dir := t.TempDir()
+		filename := filepath.Join(dir, "test.log")
+		assert.NoError(t, os.WriteFile(filename, []byte("old\n"), 0o644))
+		assert.NoError(t, os.Chmod(filename, 0o644))
+		opts := NewOptions()
+		opts.DisableConsole = true
+		opts.DisableFile = false
+		opts.DisableRotate = !rotate
+		opts.Output = dir
+		opts.FilenameEncoder = func() string {
+			return "test.log"
+		}
+
+		// the mode of the existing file is kept if FileMode is not set
+		l := New(opts)
+		l.Info("unset")
+		assert.NoError(t, l.Close())
+		info, err := os.Stat(filename)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+
+		opts.FileMode = 0o600
+		uid := os.Getuid()
+		opts.FileUID = &uid
+		l = New(opts)
+		l.Info("restricted")
+		assert.NoError(t, l.Close())
+		info, err = os.Stat(filename)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+		content, err := os.ReadFile(filename)
+		assert.NoError(t, err)
+		assert.Contains(t, string(content), "old\n")
+	}
+}
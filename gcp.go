@@ -0,0 +1,183 @@
+package log
+
+import (
+	"strconv"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// Keys of the trace correlation fields, the presets map them to the keys
+// of the logging backends, e.g. logging.googleapis.com/trace of FormatGCP.
+const (
+	TraceIDKey    = "trace_id"
+	SpanIDKey     = "span_id"
+	TraceFlagsKey = "trace_flags"
+)
+
+const (
+	gcpSourceLocationKey = "logging.googleapis.com/sourceLocation"
+	gcpTraceKey          = "logging.googleapis.com/trace"
+	gcpSpanIDKey         = "logging.googleapis.com/spanId"
+	gcpTraceSampledKey   = "logging.googleapis.com/trace_sampled"
+	gcpErrorEventType    = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+)
+
+// gcpEncoder is a zapcore.Encoder which encodes the entries in the
+// structured JSON format of Google Cloud Logging, e.g.
+//
+//	{"severity":"ERROR","timestamp":"2006-01-02T15:04:05Z","message":"failed",
+//	"logging.googleapis.com/sourceLocation":{"file":"app/main.go","line":"10","function":"main.run"},
+//	"logging.googleapis.com/trace":"projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+//	"@type":"type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent",
+//	"user_id":1}
+//
+// The trace_id, span_id and trace_flags fields are mapped to the trace keys
+// of Cloud Logging.
+type gcpEncoder struct {
+	// Encoder is the JSON encoder of the user fields
+	zapcore.Encoder
+	cfg            zapcore.EncoderConfig
+	projectID      string
+	splitTimestamp bool
+
+	traceID    string
+	spanID     string
+	traceFlags string
+}
+
+// NewGCPEncoder creates an encoder which encodes the entries in the
+// structured JSON format of Google Cloud Logging. If the projectID is not
+// empty, the trace is formatted as projects/<projectID>/traces/<trace_id>.
+// If splitTimestamp is true, the time is encoded as timestampSeconds and
+// timestampNanos. The time and caller are omitted if the corresponding keys
+// of the cfg are empty.
+func NewGCPEncoder(cfg zapcore.EncoderConfig, projectID string, splitTimestamp bool) zapcore.Encoder {
+	return &gcpEncoder{
+		Encoder:        zapcore.NewJSONEncoder(fieldsEncoderConfig(cfg)),
+		cfg:            cfg,
+		projectID:      projectID,
+		splitTimestamp: splitTimestamp,
+	}
+}
+
+func (enc *gcpEncoder) Clone() zapcore.Encoder {
+	clone := *enc
+	clone.Encoder = enc.Encoder.Clone()
+	return &clone
+}
+
+// AddString captures the trace correlation fields added by With.
+func (enc *gcpEncoder) AddString(key, val string) {
+	switch key {
+	case TraceIDKey:
+		enc.traceID = val
+	case SpanIDKey:
+		enc.spanID = val
+	case TraceFlagsKey:
+		enc.traceFlags = val
+	default:
+		enc.Encoder.AddString(key, val)
+	}
+}
+
+func (enc *gcpEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := enc.Clone().(*gcpEncoder)
+	var errStack string
+	user := make([]Field, 0, len(fields))
+	for _, f := range fields {
+		if f.Type == zapcore.StringType && (f.Key == TraceIDKey || f.Key == SpanIDKey || f.Key == TraceFlagsKey) {
+			final.AddString(f.Key, f.String)
+			continue
+		}
+		if err, ok := errorOf(f); ok && err != nil && errStack == "" {
+			errStack = errorStack(err)
+		}
+		user = append(user, f)
+	}
+
+	he := zapcore.NewJSONEncoder(zapcore.EncoderConfig{LineEnding: "\n"})
+	he.AddString("severity", gcpSeverity(ent.Level))
+	if enc.cfg.TimeKey != "" && !ent.Time.IsZero() {
+		if enc.splitTimestamp {
+			he.AddInt64("timestampSeconds", ent.Time.Unix())
+			he.AddInt("timestampNanos", ent.Time.Nanosecond())
+		} else {
+			he.AddString("timestamp", ent.Time.Format("2006-01-02T15:04:05.999999999Z07:00"))
+		}
+	}
+	he.AddString("message", ent.Message)
+	if ent.LoggerName != "" && enc.cfg.NameKey != "" {
+		he.AddString(enc.cfg.NameKey, ent.LoggerName)
+	}
+	if ent.Caller.Defined && enc.cfg.CallerKey != "" {
+		_ = he.AddObject(gcpSourceLocationKey, gcpSourceLocation(ent.Caller))
+	}
+	if final.traceID != "" {
+		trace := final.traceID
+		if enc.projectID != "" {
+			trace = "projects/" + enc.projectID + "/traces/" + trace
+		}
+		he.AddString(gcpTraceKey, trace)
+	}
+	if final.spanID != "" {
+		he.AddString(gcpSpanIDKey, final.spanID)
+	}
+	if final.traceFlags != "" {
+		flags, err := strconv.ParseUint(final.traceFlags, 16, 8)
+		he.AddBool(gcpTraceSampledKey, err == nil && flags&1 == 1)
+	}
+	if ent.Level >= ErrorLevel {
+		he.AddString("@type", gcpErrorEventType)
+	}
+	// prefers the stacktrace of the error, where it was created
+	if errStack != "" {
+		he.AddString("stack_trace", errStack)
+	} else if ent.Stack != "" {
+		he.AddString("stack_trace", ent.Stack)
+	}
+	headBuf, err := he.EncodeEntry(zapcore.Entry{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer headBuf.Free()
+	body, err := final.Encoder.EncodeEntry(zapcore.Entry{}, user)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Free()
+
+	return mergeJSON(headBuf, body, "", enc.cfg.LineEnding), nil
+}
+
+// gcpSeverity returns the Cloud Logging severity of the level.
+func gcpSeverity(lvl Level) string {
+	switch {
+	case lvl < InfoLevel:
+		return "DEBUG"
+	case lvl == InfoLevel:
+		return "INFO"
+	case lvl == WarnLevel:
+		return "WARNING"
+	case lvl == ErrorLevel:
+		return "ERROR"
+	case lvl == DPanicLevel, lvl == PanicLevel:
+		return "CRITICAL"
+	case lvl == FatalLevel:
+		return "ALERT"
+	default:
+		return "EMERGENCY"
+	}
+}
+
+func gcpSourceLocation(caller zapcore.EntryCaller) zapcore.ObjectMarshaler {
+	return zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		enc.AddString("file", callerFile(caller))
+		// line is an int64, which is a string in the proto3 JSON mapping
+		enc.AddString("line", strconv.Itoa(caller.Line))
+		if caller.Function != "" {
+			enc.AddString("function", caller.Function)
+		}
+		return nil
+	})
+}
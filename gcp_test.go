@@ -0,0 +1,77 @@
+package log
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestGCPEncoder(t *testing.T) {
+	cfg := zapcore.EncoderConfig{
+		TimeKey:   "time",
+		CallerKey: "caller",
+	}
+	ent := zapcore.Entry{
+		Level:   ErrorLevel,
+		Time:    time.Date(2006, 1, 2, 15, 4, 5, 7, time.UTC),
+		Message: "failed",
+		Caller:  zapcore.NewEntryCaller(0, "/src/app/main.go", 10, true),
+		Stack:   "main.run\n\t/src/app/main.go:10",
+	}
+
+	t.Run("with project", func(t *testing.T) {
+		enc := NewGCPEncoder(cfg, "my-project", false)
+		enc.AddString(TraceIDKey, "4bf92f3577b34da6a3ce929d0e0e4736")
+		enc.AddString("service", "api")
+		buf, err := enc.EncodeEntry(ent, []Field{
+			String(SpanIDKey, "00f067aa0ba902b7"),
+			String(TraceFlagsKey, "01"),
+			Int("user_id", 1),
+		})
+		assert.NoError(t, err)
+
+		got := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+		assert.Equal(t, map[string]interface{}{
+			"severity":  "ERROR",
+			"timestamp": "2006-01-02T15:04:05.000000007Z",
+			"message":   "failed",
+			"logging.googleapis.com/sourceLocation": map[string]interface{}{
+				"file": "app/main.go",
+				"line": "10",
+			},
+			"logging.googleapis.com/trace":         "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+			"logging.googleapis.com/spanId":        "00f067aa0ba902b7",
+			"logging.googleapis.com/trace_sampled": true,
+			"@type":                                gcpErrorEventType,
+			"stack_trace":                          "main.run\n\t/src/app/main.go:10",
+			"service":                              "api",
+			"user_id":                              float64(1),
+		}, got)
+	})
+
+	t.Run("split timestamp", func(t *testing.T) {
+		enc := NewGCPEncoder(zapcore.EncoderConfig{TimeKey: "time"}, "", true)
+		buf, err := enc.EncodeEntry(zapcore.Entry{
+			Level:   FatalLevel,
+			Time:    ent.Time,
+			Message: "hello",
+		}, []Field{String(TraceIDKey, "abc")})
+		assert.NoError(t, err)
+		assert.Equal(t, `{"severity":"ALERT","timestampSeconds":1136214245,"timestampNanos":7,"message":"hello",`+
+			`"logging.googleapis.com/trace":"abc","@type":"`+gcpErrorEventType+`"}`+"\n", buf.String())
+	})
+}
+
+func TestGCPSeverity(t *testing.T) {
+	assert.Equal(t, "DEBUG", gcpSeverity(DebugLevel))
+	assert.Equal(t, "INFO", gcpSeverity(InfoLevel))
+	assert.Equal(t, "WARNING", gcpSeverity(WarnLevel))
+	assert.Equal(t, "ERROR", gcpSeverity(ErrorLevel))
+	assert.Equal(t, "CRITICAL", gcpSeverity(DPanicLevel))
+	assert.Equal(t, "CRITICAL", gcpSeverity(PanicLevel))
+	assert.Equal(t, "ALERT", gcpSeverity(FatalLevel))
+}
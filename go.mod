@@ -3,8 +3,8 @@ module github.com/shipengqi/log
 go 1.16
 
 require (
+	github.com/go-logr/logr v1.2.4
 	github.com/spf13/pflag v1.0.10
 	github.com/stretchr/testify v1.11.1
 	go.uber.org/zap v1.28.0
-	gopkg.in/natefinch/lumberjack.v2 v2.2.1
 )
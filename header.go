@@ -0,0 +1,51 @@
+package log
+
+import (
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// fileHeaderMessage is the message of the header entry of the log files.
+const fileHeaderMessage = "log file header"
+
+// fileHeader returns a function which encodes the header entry written at
+// the start of every new log file, the entry contains the build and process
+// metadata and the effective Options.
+func fileHeader(opts *Options, enc zapcore.Encoder) func() []byte {
+	fields := []Field{
+		String("go_version", runtime.Version()),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		fields = append(fields,
+			String("module_path", bi.Main.Path),
+			String("module_version", bi.Main.Version),
+		)
+		fields = append(fields, buildSettingFields(bi)...)
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		fields = append(fields, String("hostname", hostname))
+	}
+	fields = append(fields,
+		Int("pid", os.Getpid()),
+		Strings("args", os.Args),
+		Reflect("options", opts),
+	)
+
+	clock := opts.clock()
+	return func() []byte {
+		ent := zapcore.Entry{
+			Level:   InfoLevel,
+			Time:    clock.Now(),
+			Message: fileHeaderMessage,
+		}
+		buf, err := enc.Clone().EncodeEntry(ent, fields)
+		if err != nil {
+			return nil
+		}
+		defer buf.Free()
+		return append([]byte(nil), buf.Bytes()...)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !go1.18
+// +build !go1.18
+
+package log
+
+import "runtime/debug"
+
+// buildSettingFields returns nothing, the build settings are only available
+// since go1.18.
+func buildSettingFields(_ *debug.BuildInfo) []Field {
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build go1.18
+// +build go1.18
+
+package log
+
+import "runtime/debug"
+
+// buildSettingFields returns the VCS information of the build.
+func buildSettingFields(bi *debug.BuildInfo) []Field {
+	var fields []Field
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision", "vcs.time", "vcs.modified":
+			fields = append(fields, String(s.Key, s.Value))
+		}
+	}
+	return fields
+}
@@ -0,0 +1,59 @@
+package log
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileHeader(t *testing.T) {
+	dir := t.TempDir()
+	name := "test-20060102.log"
+	opts := NewOptions()
+	opts.DisableConsole = true
+	opts.DisableFile = false
+	opts.DisableRotate = false
+	opts.FileHeader = true
+	opts.Output = dir
+	opts.FilenameEncoder = func() string {
+		return name
+	}
+	l := New(opts)
+	l.Info("first")
+	l.Info("second")
+	name = "test-20060103.log"
+	l.Info("third")
+	assert.NoError(t, l.Close())
+
+	expected := map[string][]string{
+		"test-20060102.log": {fileHeaderMessage, "first", "second"},
+		"test-20060103.log": {fileHeaderMessage, "third"},
+	}
+	for filename, messages := range expected {
+		f, err := os.Open(filepath.Join(dir, filename))
+		assert.NoError(t, err)
+
+		var got []map[string]interface{}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			entry := map[string]interface{}{}
+			assert.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+			got = append(got, entry)
+		}
+		_ = f.Close()
+
+		assert.Equal(t, len(messages), len(got))
+		for i, msg := range messages {
+			assert.Equal(t, msg, got[i]["msg"])
+		}
+		header := got[0]
+		assert.Equal(t, float64(os.Getpid()), header["pid"])
+		assert.NotEmpty(t, header["go_version"])
+		assert.NotEmpty(t, header["args"])
+		assert.Equal(t, true, header["options"].(map[string]interface{})["file-header"])
+	}
+}
@@ -0,0 +1,71 @@
+package log
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var _ Interface = (*Logger)(nil)
+
+func TestDPanic(t *testing.T) {
+	l := newContextTestLogger(t)
+	assert.NotPanics(t, func() {
+		l.DPanict("dpanic", String("key", "t"))
+		l.DPanicf("dpanic %s", "f")
+		l.DPanic("dpanic", "key", "w")
+		l.AtLevelt(DPanicLevel, "at level")
+		l.AtLevelf(DPanicLevel, "at level %d", 2)
+		l.AtLevel(DPanicLevel, "at level", "key", 3)
+	})
+	lines := readContextTestLogger(t, l)
+	assert.Equal(t, []string{
+		`{"level":"DPANIC","caller":"log/interface_test.go:15","msg":"dpanic","key":"t"}`,
+		`{"level":"DPANIC","caller":"log/interface_test.go:16","msg":"dpanic f"}`,
+		`{"level":"DPANIC","caller":"log/interface_test.go:17","msg":"dpanic","key":"w"}`,
+	}, lines[:3])
+	// the callers of AtLevel are in logger.go
+	assert.Equal(t, 6, len(lines))
+	assert.True(t, strings.HasSuffix(lines[3], `"msg":"at level"}`), lines[3])
+	assert.True(t, strings.HasSuffix(lines[4], `"msg":"at level 2"}`), lines[4])
+	assert.True(t, strings.HasSuffix(lines[5], `"msg":"at level","key":3}`), lines[5])
+
+	opts := NewOptions()
+	opts.DisableConsole = true
+	opts.DisableFile = false
+	opts.Output = t.TempDir()
+	opts.Development = true
+	l = New(opts)
+	defer func() { _ = l.Close() }()
+	assert.Panics(t, func() { l.DPanic("dpanic") })
+	assert.Panics(t, func() { l.AtLevelt(DPanicLevel, "dpanic") })
+}
+
+func TestEnabled(t *testing.T) {
+	opts := NewOptions()
+	opts.ConsoleLevel = WarnLevel.String()
+	l := New(opts)
+	assert.False(t, l.Enabled(InfoLevel))
+	assert.True(t, l.Enabled(WarnLevel))
+	assert.True(t, l.Named("child").Enabled(ErrorLevel))
+	assert.Equal(t, "child.grandchild", l.Named("child").Named("grandchild").log.Name())
+	assert.Nil(t, l.Check(InfoLevel, "disabled"))
+	assert.NotNil(t, l.Check(WarnLevel, "enabled"))
+
+	assert.True(t, Enabled(InfoLevel))
+	assert.False(t, Enabled(DebugLevel))
+}
+
+func TestPrint(t *testing.T) {
+	l := newContextTestLogger(t)
+	l.Print("a", "b", 1, 2)
+	l.Println("a", "b", 1, 2)
+	l.Printf("%s-%d", "a", 1)
+	lines := readContextTestLogger(t, l)
+	assert.Equal(t, []string{
+		`{"level":"INFO","caller":"log/interface_test.go:62","msg":"ab1 2"}`,
+		`{"level":"INFO","caller":"log/interface_test.go:63","msg":"a b 1 2"}`,
+		`{"level":"INFO","caller":"log/interface_test.go:64","msg":"a-1"}`,
+	}, lines)
+}
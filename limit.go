@@ -0,0 +1,382 @@
+package log
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// truncatedFormat is the marker appended to the truncated values, the
+	// argument is the original length in bytes.
+	truncatedFormat = "...[truncated %d bytes]"
+	// truncatedReserve is the room reserved for the marker.
+	truncatedReserve = len(truncatedFormat) + 20
+
+	// The keys of the fields added to the entries which are too large.
+	droppedFieldsKey = "dropped_fields"
+	entryIDKey       = "entry_id"
+	entryPartKey     = "entry_part"
+	entryPartsKey    = "entry_parts"
+)
+
+var _limitPool = buffer.NewPool()
+
+// limitEncoder is a zapcore.Encoder which limits the sizes of the messages,
+// the string and byte fields and the encoded entries.
+//
+// The oversized messages and fields are truncated with a marker annotating
+// the original length, e.g. "aaa...[truncated 1048576 bytes]". The oversized
+// entries are shrunk by truncating the stacktrace, then the message and
+// dropping the fields, or split into continuation entries sharing an
+// entry_id if split is true, the stacktrace of the first entry is truncated
+// to leave room for the message.
+type limitEncoder struct {
+	zapcore.Encoder
+	maxMessage int
+	maxField   int
+	maxEntry   int
+	split      bool
+}
+
+// newLimitEncoder wraps the encoder with the size limits of the options, the
+// encoder is returned as is if no limit is set.
+func newLimitEncoder(opts *Options, enc zapcore.Encoder) zapcore.Encoder {
+	if opts.MaxMessageBytes <= 0 && opts.MaxFieldBytes <= 0 && opts.MaxEntryBytes <= 0 {
+		return enc
+	}
+	return &limitEncoder{
+		Encoder:    enc,
+		maxMessage: opts.MaxMessageBytes,
+		maxField:   opts.MaxFieldBytes,
+		maxEntry:   opts.MaxEntryBytes,
+		split:      opts.SplitEntries,
+	}
+}
+
+func (enc *limitEncoder) Clone() zapcore.Encoder {
+	clone := *enc
+	clone.Encoder = enc.Encoder.Clone()
+	return &clone
+}
+
+func (enc *limitEncoder) AddString(key, val string) {
+	enc.Encoder.AddString(key, truncateString(val, enc.maxField))
+}
+
+func (enc *limitEncoder) AddByteString(key string, val []byte) {
+	if enc.maxField > 0 && len(val) > enc.maxField {
+		enc.Encoder.AddString(key, truncateString(string(val), enc.maxField))
+		return
+	}
+	enc.Encoder.AddByteString(key, val)
+}
+
+func (enc *limitEncoder) AddBinary(key string, val []byte) {
+	if enc.maxField > 0 && len(val) > enc.maxField {
+		enc.Encoder.AddBinary(key, val[:enc.maxField])
+		enc.Encoder.AddInt(key+"_bytes", len(val))
+		return
+	}
+	enc.Encoder.AddBinary(key, val)
+}
+
+func (enc *limitEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	ent.Message = truncateString(ent.Message, enc.maxMessage)
+	fields = enc.truncateFields(fields)
+
+	buf, err := enc.Encoder.EncodeEntry(ent, fields)
+	if err != nil || enc.maxEntry <= 0 || buf.Len() <= enc.maxEntry {
+		return buf, err
+	}
+	buf.Free()
+	if enc.split {
+		return enc.splitEntry(ent, fields)
+	}
+	if ent.Stack != "" {
+		// the stacktrace is truncated before the message
+		if ent, err = enc.shrinkStack(ent, fields, 0); err != nil {
+			return nil, err
+		}
+		buf, err = enc.Encoder.EncodeEntry(ent, fields)
+		if err != nil || buf.Len() <= enc.maxEntry {
+			return buf, err
+		}
+		buf.Free()
+	}
+	return enc.shrinkEntry(ent, fields)
+}
+
+// truncateFields returns the fields with the oversized string and byte
+// fields truncated, the fields are copied only if any field is truncated.
+func (enc *limitEncoder) truncateFields(fields []zapcore.Field) []zapcore.Field {
+	if enc.maxField <= 0 {
+		return fields
+	}
+	var truncated []zapcore.Field
+	for i, f := range fields {
+		var replaced []zapcore.Field
+		switch f.Type {
+		case zapcore.StringType:
+			if len(f.String) > enc.maxField {
+				replaced = []Field{String(f.Key, truncateString(f.String, enc.maxField))}
+			}
+		case zapcore.ByteStringType:
+			if b, ok := f.Interface.([]byte); ok && len(b) > enc.maxField {
+				replaced = []Field{String(f.Key, truncateString(string(b), enc.maxField))}
+			}
+		case zapcore.BinaryType:
+			if b, ok := f.Interface.([]byte); ok && len(b) > enc.maxField {
+				replaced = []Field{Binary(f.Key, b[:enc.maxField]), Int(f.Key+"_bytes", len(b))}
+			}
+		}
+		if replaced == nil {
+			if truncated != nil {
+				truncated = append(truncated, f)
+			}
+			continue
+		}
+		if truncated == nil {
+			truncated = append(make([]zapcore.Field, 0, len(fields)+1), fields[:i]...)
+		}
+		truncated = append(truncated, replaced...)
+	}
+	if truncated == nil {
+		return fields
+	}
+	return truncated
+}
+
+// shrinkStack truncates the stacktrace of the entry so that the encoded
+// entry fits in maxEntry with room bytes left, the stacktrace is replaced by
+// the marker if the entry doesn't fit without it.
+func (enc *limitEncoder) shrinkStack(ent zapcore.Entry, fields []zapcore.Field, room int) (zapcore.Entry, error) {
+	if ent.Stack == "" {
+		return ent, nil
+	}
+	stack := ent.Stack
+	ent.Stack = ""
+	buf, err := enc.Encoder.EncodeEntry(ent, fields)
+	if err != nil {
+		return ent, err
+	}
+	available := enc.maxEntry - buf.Len() - room - truncatedReserve
+	buf.Free()
+	for {
+		if available < 0 {
+			available = 0
+		}
+		ent.Stack = truncateTo(stack, available)
+		buf, err = enc.Encoder.EncodeEntry(ent, fields)
+		if err != nil {
+			return ent, err
+		}
+		over := buf.Len() + room - enc.maxEntry
+		buf.Free()
+		if over <= 0 || available == 0 {
+			return ent, nil
+		}
+		available -= over
+	}
+}
+
+// shrinkEntry truncates the message so that the encoded entry fits in
+// maxEntry, the fields are dropped if they do not fit.
+func (enc *limitEncoder) shrinkEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	available, err := enc.available(ent, fields)
+	if err != nil {
+		return nil, err
+	}
+	if available <= 0 {
+		fields = []Field{Int(droppedFieldsKey, len(fields))}
+		if available, err = enc.available(ent, fields); err != nil {
+			return nil, err
+		}
+	}
+	return enc.encodeTruncated(ent, fields, available)
+}
+
+// encodeTruncated encodes the entry with the message truncated to the
+// available bytes, it retries with less bytes until the entry fits, as the
+// message may expand when it is escaped.
+func (enc *limitEncoder) encodeTruncated(ent zapcore.Entry, fields []zapcore.Field, available int) (*buffer.Buffer, error) {
+	msg := ent.Message
+	for {
+		if available < 0 {
+			available = 0
+		}
+		ent.Message = truncateTo(msg, available)
+		buf, err := enc.Encoder.EncodeEntry(ent, fields)
+		if err != nil || buf.Len() <= enc.maxEntry || available == 0 {
+			return buf, err
+		}
+		available -= buf.Len() - enc.maxEntry
+		buf.Free()
+	}
+}
+
+// splitEntry splits the message into continuation entries sharing an id,
+// the first entry has the fields.
+func (enc *limitEncoder) splitEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	id := newEntryID()
+	// measures with the max part numbers
+	partFields := func(part, parts int) []zapcore.Field {
+		return []Field{String(entryIDKey, id), Int(entryPartKey, part), Int(entryPartsKey, parts)}
+	}
+	first := append(append([]zapcore.Field(nil), fields...), partFields(1<<20, 1<<20)...)
+	if ent.Stack != "" {
+		// the stacktrace leaves room for a chunk of the message in the
+		// first part
+		msg, room := ent.Message, enc.maxEntry/2
+		if len(msg) < room {
+			room = len(msg)
+		}
+		ent.Message = ""
+		var err error
+		if ent, err = enc.shrinkStack(ent, first, room); err != nil {
+			return nil, err
+		}
+		ent.Message = msg
+	}
+	firstAvail, err := enc.available(ent, first)
+	if err != nil {
+		return nil, err
+	}
+	if firstAvail <= 0 {
+		fields = []Field{Int(droppedFieldsKey, len(fields))}
+		first = append(append([]zapcore.Field(nil), fields...), partFields(1<<20, 1<<20)...)
+		if firstAvail, err = enc.available(ent, first); err != nil {
+			return nil, err
+		}
+	}
+	cont := ent
+	cont.Stack = ""
+	contAvail, err := enc.available(cont, partFields(1<<20, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if firstAvail <= 0 || contAvail <= 0 {
+		return enc.shrinkEntry(ent, fields)
+	}
+
+	// no marker is appended to the chunks
+	chunks, err := enc.splitMessage(ent.Message,
+		splitPart{ent: ent, fields: first, size: firstAvail + truncatedReserve},
+		splitPart{ent: cont, fields: partFields(1<<20, 1<<20), size: contAvail + truncatedReserve})
+	if err != nil {
+		return nil, err
+	}
+	out := _limitPool.Get()
+	for i, chunk := range chunks {
+		part, pf := cont, partFields(i+1, len(chunks))
+		if i == 0 {
+			part = ent
+			pf = append(append([]zapcore.Field(nil), fields...), pf...)
+		}
+		part.Message = chunk
+		buf, err := enc.Encoder.EncodeEntry(part, pf)
+		if err != nil {
+			out.Free()
+			return nil, err
+		}
+		_, _ = out.Write(buf.Bytes())
+		buf.Free()
+	}
+	return out, nil
+}
+
+// splitPart is the entry, the fields and the max message bytes of a part
+// of a split entry.
+type splitPart struct {
+	ent    zapcore.Entry
+	fields []zapcore.Field
+	size   int
+}
+
+// splitMessage splits the message into chunks, the first chunk is encoded
+// as the first part and the others as the continuation parts. Every chunk
+// is shrunk until its encoded entry fits in maxEntry, as the message may
+// expand when it is escaped.
+func (enc *limitEncoder) splitMessage(msg string, first, cont splitPart) ([]string, error) {
+	var chunks []string
+	part := first
+	for {
+		// a chunk has at least one rune
+		_, minLen := utf8.DecodeRuneInString(msg)
+		size, n := part.size, len(msg)
+		for {
+			if size < n {
+				n = runeBoundary(msg, size)
+			}
+			if n < minLen {
+				n = minLen
+			}
+			part.ent.Message = msg[:n]
+			buf, err := enc.Encoder.EncodeEntry(part.ent, part.fields)
+			if err != nil {
+				return nil, err
+			}
+			over := buf.Len() - enc.maxEntry
+			buf.Free()
+			if over <= 0 || n <= minLen {
+				break
+			}
+			size = n - over
+		}
+		chunks = append(chunks, msg[:n])
+		msg = msg[n:]
+		if msg == "" {
+			return chunks, nil
+		}
+		part = cont
+	}
+}
+
+// available returns the bytes available for the message and the marker of
+// the entry.
+func (enc *limitEncoder) available(ent zapcore.Entry, fields []zapcore.Field) (int, error) {
+	ent.Message = ""
+	buf, err := enc.Encoder.EncodeEntry(ent, fields)
+	if err != nil {
+		return 0, err
+	}
+	defer buf.Free()
+	return enc.maxEntry - buf.Len() - truncatedReserve, nil
+}
+
+// truncateString truncates the string to maxLen bytes at a rune boundary and
+// appends the marker, the string is returned as is if maxLen is not positive.
+func truncateString(s string, maxLen int) string {
+	if maxLen <= 0 {
+		return s
+	}
+	return truncateTo(s, maxLen)
+}
+
+// truncateTo truncates the string to n bytes at a rune boundary and appends
+// the marker.
+func truncateTo(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:runeBoundary(s, n)] + fmt.Sprintf(truncatedFormat, len(s))
+}
+
+// runeBoundary returns the largest index not greater than n which is at a
+// rune boundary of s.
+func runeBoundary(s string, n int) int {
+	for n > 0 && n < len(s) && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return n
+}
+
+func newEntryID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,186 @@
+package log
+
+import (
+	"bufio"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func newTestLimitEncoder(opts *Options) zapcore.Encoder {
+	return newLimitEncoder(opts, zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+		MessageKey:  "msg",
+		LevelKey:    "level",
+		LineEnding:  "\n",
+		EncodeLevel: zapcore.LowercaseLevelEncoder,
+	}))
+}
+
+func TestLimitEncoderTruncate(t *testing.T) {
+	opts := NewOptions()
+	opts.MaxMessageBytes = 5
+	opts.MaxFieldBytes = 3
+	enc := newTestLimitEncoder(opts)
+	enc.AddString("ctx", "abcdef")
+
+	buf, err := enc.EncodeEntry(zapcore.Entry{Message: "hello world"}, []Field{
+		String("s", "abéc"),
+		ByteString("b", []byte("abcdef")),
+		Binary("bin", []byte("abcdef")),
+		String("short", "abc"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"level":"info","msg":"hello...[truncated 11 bytes]","ctx":"abc...[truncated 6 bytes]",`+
+		`"s":"ab...[truncated 5 bytes]","b":"abc...[truncated 6 bytes]","bin":"YWJj","bin_bytes":6,"short":"abc"}`+"\n",
+		buf.String())
+
+	_, ok := newTestLimitEncoder(NewOptions()).(*limitEncoder)
+	assert.False(t, ok)
+}
+
+func TestLimitEncoderEntry(t *testing.T) {
+	msg := strings.Repeat("x", 1000)
+
+	t.Run("shrink", func(t *testing.T) {
+		opts := NewOptions()
+		opts.MaxEntryBytes = 200
+		enc := newTestLimitEncoder(opts)
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: msg}, []Field{Int("id", 1)})
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, buf.Len(), 200)
+
+		entry := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, float64(1), entry["id"])
+		assert.True(t, strings.HasSuffix(entry["msg"].(string), "...[truncated 1000 bytes]"))
+
+		// the fields are dropped if they do not fit
+		buf, err = enc.EncodeEntry(zapcore.Entry{Message: "hello"}, []Field{String("body", msg)})
+		assert.NoError(t, err)
+		assert.Equal(t, `{"level":"info","msg":"hello","dropped_fields":1}`+"\n", buf.String())
+	})
+
+	t.Run("split", func(t *testing.T) {
+		opts := NewOptions()
+		opts.MaxEntryBytes = 200
+		opts.SplitEntries = true
+		enc := newTestLimitEncoder(opts)
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: msg}, []Field{Int("id", 1)})
+		assert.NoError(t, err)
+
+		var (
+			ids     = map[string]bool{}
+			message string
+			parts   int
+		)
+		scanner := bufio.NewScanner(strings.NewReader(buf.String()))
+		for scanner.Scan() {
+			assert.LessOrEqual(t, len(scanner.Bytes())+1, 200)
+			entry := struct {
+				Msg   string `json:"msg"`
+				ID    string `json:"entry_id"`
+				Part  int    `json:"entry_part"`
+				Parts int    `json:"entry_parts"`
+				Field *int   `json:"id"`
+			}{}
+			assert.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+			parts++
+			assert.Equal(t, parts, entry.Part)
+			assert.Equal(t, parts == 1, entry.Field != nil)
+			ids[entry.ID] = true
+			message += entry.Msg
+		}
+		assert.Greater(t, parts, 5)
+		assert.Equal(t, 1, len(ids))
+		assert.Equal(t, msg, message)
+	})
+}
+
+func TestLimitEncoderEscapedEntry(t *testing.T) {
+	for _, msg := range []string{
+		strings.Repeat(`"`, 1000),
+		strings.Repeat("\x01", 1000),
+		strings.Repeat("é\"", 500),
+	} {
+		opts := NewOptions()
+		opts.MaxEntryBytes = 200
+		enc := newTestLimitEncoder(opts)
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: msg}, []Field{Int("id", 1)})
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, buf.Len(), 200)
+		assert.Contains(t, buf.String(), "[truncated")
+
+		opts.SplitEntries = true
+		enc = newTestLimitEncoder(opts)
+		buf, err = enc.EncodeEntry(zapcore.Entry{Message: msg}, []Field{Int("id", 1)})
+		assert.NoError(t, err)
+		var message string
+		scanner := bufio.NewScanner(strings.NewReader(buf.String()))
+		for scanner.Scan() {
+			assert.LessOrEqual(t, len(scanner.Bytes())+1, 200)
+			entry := struct {
+				Msg string `json:"msg"`
+			}{}
+			assert.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+			message += entry.Msg
+		}
+		assert.Equal(t, msg, message)
+	}
+}
+
+func TestLimitEncoderStack(t *testing.T) {
+	newStackLogger := func(t *testing.T, maxEntry int, split bool) *Logger {
+		opts := NewOptions()
+		opts.DisableConsole = true
+		opts.DisableFile = false
+		opts.DisableFileTime = true
+		opts.DisableFileCaller = true
+		opts.Output = t.TempDir()
+		opts.StacktraceLevel = ErrorLevel.String()
+		opts.MaxEntryBytes = maxEntry
+		opts.SplitEntries = split
+		return New(opts)
+	}
+	var deep func(n int, f func())
+	deep = func(n int, f func()) {
+		if n == 0 {
+			f()
+			return
+		}
+		deep(n-1, f)
+	}
+
+	t.Run("truncate the stack before the message", func(t *testing.T) {
+		l := newStackLogger(t, 256, false)
+		deep(32, func() { l.Errort("failed", String("user", "alice")) })
+		lines := readContextTestLogger(t, l)
+		assert.Equal(t, 1, len(lines))
+		assert.LessOrEqual(t, len(lines[0])+1, 256)
+		assert.Contains(t, lines[0], `"msg":"failed","user":"alice","stack":"github.com/shipengqi/log.`)
+		assert.Contains(t, lines[0], `...[truncated `)
+	})
+
+	t.Run("drop the stack of the large message", func(t *testing.T) {
+		l := newStackLogger(t, 256, false)
+		deep(32, func() { l.Errort(strings.Repeat("x", 1024)) })
+		lines := readContextTestLogger(t, l)
+		assert.Equal(t, 1, len(lines))
+		assert.LessOrEqual(t, len(lines[0])+1, 256)
+		assert.Regexp(t, `"stack":"\.\.\.\[truncated \d+ bytes\]"`, lines[0])
+	})
+
+	t.Run("split", func(t *testing.T) {
+		l := newStackLogger(t, 512, true)
+		deep(32, func() { l.Errort(strings.Repeat("x", 1024)) })
+		lines := readContextTestLogger(t, l)
+		assert.Greater(t, len(lines), 1)
+		for _, line := range lines {
+			assert.LessOrEqual(t, len(line)+1, 512)
+		}
+		assert.Contains(t, lines[0], `"stack":"github.com/shipengqi/log.`)
+		assert.NotContains(t, lines[1], `"stack"`)
+	})
+}
@@ -2,6 +2,8 @@
 package log
 
 import (
+	"context"
+	"errors"
 	"log"
 
 	"go.uber.org/zap"
@@ -66,6 +68,10 @@ type Interface interface {
 	Errorf(template string, args ...interface{})
 	Error(msg string, keysAndValues ...interface{})
 
+	DPanict(msg string, fields ...Field)
+	DPanicf(template string, args ...interface{})
+	DPanic(msg string, keysAndValues ...interface{})
+
 	Panict(msg string, fields ...Field)
 	Panicf(template string, args ...interface{})
 	Panic(msg string, keysAndValues ...interface{})
@@ -78,10 +84,28 @@ type Interface interface {
 	AtLevelf(level Level, template string, args ...interface{})
 	AtLevel(level Level, msg string, keysAndValues ...interface{})
 
+	Print(args ...interface{})
+	Printf(format string, args ...interface{})
+	Println(args ...interface{})
+
 	// WithValues creates a child logger and adds some Field of
 	// context to this logger.
 	WithValues(fields ...Field) *Logger
 
+	// Named creates a child logger and adds a new path segment to the
+	// logger's name.
+	Named(name string) *Logger
+
+	// Sugared returns sugared logger.
+	Sugared() *zap.SugaredLogger
+
+	// Enabled reports whether the logger logs the messages at the level.
+	Enabled(level Level) bool
+
+	// Check returns a CheckedEntry if logging a message at the specified
+	// level is enabled.
+	Check(lvl Level, msg string) *CheckedEntry
+
 	// Flush calls the underlying Core's Sync method, flushing any buffered
 	// log entries. Applications should take care to call Sync before exiting.
 	Flush() error
@@ -90,11 +114,44 @@ type Interface interface {
 	Close() error
 }
 
-// Configure sets up the global logger.
+// ContextLogger is the ctx-first logging methods of Logger, which add the
+// fields of the ctx to the entries. It's separated from Interface, so that
+// the existing implementations of Interface don't break.
+type ContextLogger interface {
+	DebugContext(ctx context.Context, msg string, keysAndValues ...interface{})
+	InfoContext(ctx context.Context, msg string, keysAndValues ...interface{})
+	WarnContext(ctx context.Context, msg string, keysAndValues ...interface{})
+	ErrorContext(ctx context.Context, msg string, keysAndValues ...interface{})
+	DPanicContext(ctx context.Context, msg string, keysAndValues ...interface{})
+	PanicContext(ctx context.Context, msg string, keysAndValues ...interface{})
+	FatalContext(ctx context.Context, msg string, keysAndValues ...interface{})
+}
+
+// Configure sets up the global logger, and closes the previous one. The log
+// file writer of the previous logger is shared with the new logger if it
+// writes to the same logfile series with the compatible options, so that
+// the children of the previous logger keep writing to the file. Otherwise
+// the file writes of the children fail after the previous logger is closed,
+// which is closed before the new logger is created if their options of the
+// logfile series conflict.
 func Configure(opts *Options) {
-	l := New(opts)
+	prev := _globalL
+	l, err := newLogger(opts)
+	if errors.Is(err, errWriterInUse) && prev != nil {
+		// the previous logger may write to the logfile series with the
+		// other options
+		_ = prev.Close()
+		prev = nil
+		l, err = newLogger(opts)
+	}
+	if err != nil {
+		panic(err)
+	}
 	_globalL = l
 	zap.RedirectStdLog(_globalL.log)
+	if prev != nil {
+		_ = prev.Close()
+	}
 }
 
 // Debugt logs a message at DebugLevel.
@@ -157,6 +214,23 @@ func Error(msg string, keysAndValues ...interface{}) {
 	_globalL.Error(msg, keysAndValues...)
 }
 
+// DPanict logs a message at DPanicLevel, then panics in development.
+func DPanict(msg string, fields ...Field) {
+	_globalL.DPanict(msg, fields...)
+}
+
+// DPanicf uses fmt.Sprintf to log a templated message at DPanicLevel, then
+// panics in development.
+func DPanicf(template string, args ...interface{}) {
+	_globalL.DPanicf(template, args...)
+}
+
+// DPanic logs a message with some additional context at DPanicLevel, then
+// panics in development.
+func DPanic(msg string, keysAndValues ...interface{}) {
+	_globalL.DPanic(msg, keysAndValues...)
+}
+
 // Panict logs a message at PanicLevel, then panics.
 func Panict(msg string, fields ...Field) {
 	_globalL.Panict(msg, fields...)
@@ -187,17 +261,18 @@ func Fatal(msg string, keysAndValues ...interface{}) {
 	_globalL.Fatal(msg, keysAndValues...)
 }
 
-// Print logs a message at level Print.
+// Print logs a message at InfoLevel.
 func Print(args ...interface{}) {
 	_globalL.Print(args...)
 }
 
-// Println logs a message at level Print.
+// Println logs a message at InfoLevel, the operands are separated by
+// spaces like fmt.Println, without the trailing newline.
 func Println(args ...interface{}) {
 	_globalL.Println(args...)
 }
 
-// Printf logs a message at level Print.
+// Printf logs a message at InfoLevel.
 func Printf(format string, args ...interface{}) {
 	_globalL.Printf(format, args...)
 }
@@ -223,6 +298,17 @@ func WithValues(fields ...Field) *Logger {
 	return _globalL.WithValues(fields...)
 }
 
+// Named creates a child logger of the global logger and adds a new path
+// segment to the logger's name.
+func Named(name string) *Logger {
+	return _globalL.Named(name)
+}
+
+// Enabled reports whether the global logger logs the messages at the level.
+func Enabled(level Level) bool {
+	return _globalL.Enabled(level)
+}
+
 // Flush calls the underlying Core's Sync method, flushing any buffered
 // log entries. Applications should take care to call Sync before exiting.
 func Flush() error { return _globalL.Flush() }
@@ -0,0 +1,508 @@
+package log
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var _logfmtPool = buffer.NewPool()
+
+// logfmtEncoder is a zapcore.Encoder which encodes the entries in logfmt,
+// e.g. time=2006-01-02T15:04:05Z level=info msg="hello world" user.id=1
+//
+// Nested objects and namespaces are flattened with dotted keys, e.g.
+// user.name=foo, arrays are flattened with the indexes as keys, e.g.
+// ids.0=1 ids.1=2. Empty objects and arrays are encoded as {} and [].
+type logfmtEncoder struct {
+	*zapcore.EncoderConfig
+	buf *buffer.Buffer
+	// prefixes are the keys of the open namespaces and nested objects
+	prefixes []string
+}
+
+// NewLogfmtEncoder creates an encoder which encodes the entries in logfmt.
+func NewLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	if cfg.LineEnding == "" {
+		cfg.LineEnding = zapcore.DefaultLineEnding
+	}
+	return &logfmtEncoder{
+		EncoderConfig: &cfg,
+		buf:           _logfmtPool.Get(),
+	}
+}
+
+func (enc *logfmtEncoder) Clone() zapcore.Encoder {
+	clone := enc.clone()
+	_, _ = clone.buf.Write(enc.buf.Bytes())
+	return clone
+}
+
+func (enc *logfmtEncoder) clone() *logfmtEncoder {
+	return &logfmtEncoder{
+		EncoderConfig: enc.EncoderConfig,
+		buf:           _logfmtPool.Get(),
+		prefixes:      append([]string(nil), enc.prefixes...),
+	}
+}
+
+func (enc *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := enc.clone()
+	// the entry keys are never prefixed by the namespaces
+	final.prefixes = nil
+
+	if final.TimeKey != "" && !ent.Time.IsZero() {
+		final.AddTime(final.TimeKey, ent.Time)
+	}
+	if final.LevelKey != "" && final.EncodeLevel != nil {
+		final.addKey(final.LevelKey)
+		final.encodeValue(func(arr zapcore.PrimitiveArrayEncoder) {
+			final.EncodeLevel(ent.Level, arr)
+		}, ent.Level.String())
+	}
+	if ent.LoggerName != "" && final.NameKey != "" {
+		final.addKey(final.NameKey)
+		nameEncoder := final.EncodeName
+		if nameEncoder == nil {
+			nameEncoder = zapcore.FullNameEncoder
+		}
+		final.encodeValue(func(arr zapcore.PrimitiveArrayEncoder) {
+			nameEncoder(ent.LoggerName, arr)
+		}, ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		if final.CallerKey != "" && final.EncodeCaller != nil {
+			final.addKey(final.CallerKey)
+			final.encodeValue(func(arr zapcore.PrimitiveArrayEncoder) {
+				final.EncodeCaller(ent.Caller, arr)
+			}, ent.Caller.String())
+		}
+		if final.FunctionKey != "" {
+			final.AddString(final.FunctionKey, ent.Caller.Function)
+		}
+	}
+	if final.MessageKey != "" {
+		final.AddString(final.MessageKey, ent.Message)
+	}
+	if enc.buf.Len() > 0 {
+		final.addSeparator()
+		_, _ = final.buf.Write(enc.buf.Bytes())
+	}
+
+	final.prefixes = append(final.prefixes, enc.prefixes...)
+	for i := range fields {
+		fields[i].AddTo(final)
+	}
+	final.prefixes = nil
+
+	if ent.Stack != "" && final.StacktraceKey != "" {
+		final.AddString(final.StacktraceKey, ent.Stack)
+	}
+	final.buf.AppendString(final.LineEnding)
+	return final.buf, nil
+}
+
+func (enc *logfmtEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	key = enc.fullKey(key)
+	cur := enc.buf.Len()
+	err := arr.MarshalLogArray(&logfmtArrayEncoder{enc: enc, key: key})
+	if cur == enc.buf.Len() {
+		enc.addRawKey(key)
+		enc.buf.AppendString("[]")
+	}
+	return err
+}
+
+func (enc *logfmtEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	return enc.addObject(enc.fullKey(key), obj)
+}
+
+func (enc *logfmtEncoder) addObject(fullKey string, obj zapcore.ObjectMarshaler) error {
+	cur := enc.buf.Len()
+	prefixes := enc.prefixes
+	enc.prefixes = []string{fullKey}
+	err := obj.MarshalLogObject(enc)
+	enc.prefixes = prefixes
+	if cur == enc.buf.Len() {
+		enc.addRawKey(fullKey)
+		enc.buf.AppendString("{}")
+	}
+	return err
+}
+
+func (enc *logfmtEncoder) AddBinary(key string, val []byte) {
+	enc.AddString(key, base64.StdEncoding.EncodeToString(val))
+}
+
+func (enc *logfmtEncoder) AddByteString(key string, val []byte) {
+	enc.addKey(key)
+	enc.appendString(string(val))
+}
+
+func (enc *logfmtEncoder) AddBool(key string, val bool) {
+	enc.addKey(key)
+	enc.buf.AppendBool(val)
+}
+
+func (enc *logfmtEncoder) AddComplex128(key string, val complex128) {
+	enc.addKey(key)
+	enc.appendComplex(val, 64)
+}
+
+func (enc *logfmtEncoder) AddComplex64(key string, val complex64) {
+	enc.addKey(key)
+	enc.appendComplex(complex128(val), 32)
+}
+
+func (enc *logfmtEncoder) AddDuration(key string, val time.Duration) {
+	enc.addKey(key)
+	enc.appendDuration(val)
+}
+
+func (enc *logfmtEncoder) AddFloat64(key string, val float64) {
+	enc.addKey(key)
+	enc.appendFloat(val, 64)
+}
+
+func (enc *logfmtEncoder) AddFloat32(key string, val float32) {
+	enc.addKey(key)
+	enc.appendFloat(float64(val), 32)
+}
+
+func (enc *logfmtEncoder) AddInt(key string, val int) { enc.AddInt64(key, int64(val)) }
+
+func (enc *logfmtEncoder) AddInt64(key string, val int64) {
+	enc.addKey(key)
+	enc.buf.AppendInt(val)
+}
+
+func (enc *logfmtEncoder) AddInt32(key string, val int32) { enc.AddInt64(key, int64(val)) }
+
+func (enc *logfmtEncoder) AddInt16(key string, val int16) { enc.AddInt64(key, int64(val)) }
+
+func (enc *logfmtEncoder) AddInt8(key string, val int8) { enc.AddInt64(key, int64(val)) }
+
+func (enc *logfmtEncoder) AddString(key, val string) {
+	enc.addKey(key)
+	enc.appendString(val)
+}
+
+func (enc *logfmtEncoder) AddTime(key string, val time.Time) {
+	enc.addKey(key)
+	enc.appendTime(val)
+}
+
+func (enc *logfmtEncoder) AddUint(key string, val uint) { enc.AddUint64(key, uint64(val)) }
+
+func (enc *logfmtEncoder) AddUint64(key string, val uint64) {
+	enc.addKey(key)
+	enc.buf.AppendUint(val)
+}
+
+func (enc *logfmtEncoder) AddUint32(key string, val uint32) { enc.AddUint64(key, uint64(val)) }
+
+func (enc *logfmtEncoder) AddUint16(key string, val uint16) { enc.AddUint64(key, uint64(val)) }
+
+func (enc *logfmtEncoder) AddUint8(key string, val uint8) { enc.AddUint64(key, uint64(val)) }
+
+func (enc *logfmtEncoder) AddUintptr(key string, val uintptr) { enc.AddUint64(key, uint64(val)) }
+
+func (enc *logfmtEncoder) AddReflected(key string, val interface{}) error {
+	enc.addKey(key)
+	return enc.appendReflected(val)
+}
+
+func (enc *logfmtEncoder) OpenNamespace(key string) {
+	enc.prefixes = append(enc.prefixes, key)
+}
+
+func (enc *logfmtEncoder) fullKey(key string) string {
+	if len(enc.prefixes) == 0 {
+		return key
+	}
+	return strings.Join(enc.prefixes, ".") + "." + key
+}
+
+func (enc *logfmtEncoder) addKey(key string) {
+	enc.addRawKey(enc.fullKey(key))
+}
+
+func (enc *logfmtEncoder) addRawKey(key string) {
+	enc.addSeparator()
+	if strings.IndexFunc(key, invalidKeyRune) >= 0 {
+		key = strings.Map(func(r rune) rune {
+			if invalidKeyRune(r) {
+				return '_'
+			}
+			return r
+		}, key)
+	}
+	enc.buf.AppendString(key)
+	enc.buf.AppendByte('=')
+}
+
+func (enc *logfmtEncoder) addSeparator() {
+	if enc.buf.Len() > 0 {
+		enc.buf.AppendByte(' ')
+	}
+}
+
+// encodeValue encodes a value with the given zapcore encoder function, the
+// appended values are joined with commas. If the function appends nothing,
+// the fallback value is used.
+func (enc *logfmtEncoder) encodeValue(fn func(zapcore.PrimitiveArrayEncoder), fallback string) {
+	arr := &logfmtValueEncoder{}
+	fn(arr)
+	if len(arr.elems) == 0 {
+		enc.appendString(fallback)
+		return
+	}
+	enc.appendString(strings.Join(arr.elems, ","))
+}
+
+func (enc *logfmtEncoder) appendString(s string) {
+	if needsQuote(s) {
+		enc.buf.AppendString(strconv.Quote(s))
+		return
+	}
+	enc.buf.AppendString(s)
+}
+
+func (enc *logfmtEncoder) appendFloat(val float64, bitSize int) {
+	switch {
+	case math.IsNaN(val):
+		enc.buf.AppendString("NaN")
+	case math.IsInf(val, 1):
+		enc.buf.AppendString("+Inf")
+	case math.IsInf(val, -1):
+		enc.buf.AppendString("-Inf")
+	default:
+		enc.buf.AppendFloat(val, bitSize)
+	}
+}
+
+func (enc *logfmtEncoder) appendComplex(val complex128, bitSize int) {
+	enc.buf.AppendString(strconv.FormatComplex(val, 'f', -1, bitSize*2))
+}
+
+func (enc *logfmtEncoder) appendDuration(val time.Duration) {
+	if enc.EncodeDuration == nil {
+		enc.buf.AppendInt(int64(val))
+		return
+	}
+	enc.encodeValue(func(arr zapcore.PrimitiveArrayEncoder) {
+		enc.EncodeDuration(val, arr)
+	}, strconv.FormatInt(int64(val), 10))
+}
+
+func (enc *logfmtEncoder) appendTime(val time.Time) {
+	if enc.EncodeTime == nil {
+		enc.buf.AppendInt(val.UnixNano())
+		return
+	}
+	enc.encodeValue(func(arr zapcore.PrimitiveArrayEncoder) {
+		enc.EncodeTime(val, arr)
+	}, strconv.FormatInt(val.UnixNano(), 10))
+}
+
+func (enc *logfmtEncoder) appendReflected(val interface{}) error {
+	if val == nil {
+		enc.buf.AppendString("null")
+		return nil
+	}
+	data, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	enc.appendString(string(data))
+	return nil
+}
+
+// invalidKeyRune reports whether the rune is not allowed in logfmt keys,
+// it is replaced with '_'.
+func invalidKeyRune(r rune) bool {
+	return r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError
+}
+
+// needsQuote reports whether the logfmt value must be quoted.
+func needsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError ||
+			r == 0x7f || !strconv.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// logfmtArrayEncoder flattens the array elements with the indexes as keys.
+type logfmtArrayEncoder struct {
+	enc *logfmtEncoder
+	key string
+	i   int
+}
+
+func (a *logfmtArrayEncoder) nextKey() string {
+	key := a.key + "." + strconv.Itoa(a.i)
+	a.i++
+	return key
+}
+
+func (a *logfmtArrayEncoder) addKey() {
+	a.enc.addRawKey(a.nextKey())
+}
+
+func (a *logfmtArrayEncoder) AppendArray(arr zapcore.ArrayMarshaler) error {
+	key := a.nextKey()
+	cur := a.enc.buf.Len()
+	err := arr.MarshalLogArray(&logfmtArrayEncoder{enc: a.enc, key: key})
+	if cur == a.enc.buf.Len() {
+		a.enc.addRawKey(key)
+		a.enc.buf.AppendString("[]")
+	}
+	return err
+}
+
+func (a *logfmtArrayEncoder) AppendObject(obj zapcore.ObjectMarshaler) error {
+	return a.enc.addObject(a.nextKey(), obj)
+}
+
+func (a *logfmtArrayEncoder) AppendReflected(val interface{}) error {
+	a.addKey()
+	return a.enc.appendReflected(val)
+}
+
+func (a *logfmtArrayEncoder) AppendBool(val bool) {
+	a.addKey()
+	a.enc.buf.AppendBool(val)
+}
+
+func (a *logfmtArrayEncoder) AppendByteString(val []byte) {
+	a.addKey()
+	a.enc.appendString(string(val))
+}
+
+func (a *logfmtArrayEncoder) AppendComplex128(val complex128) {
+	a.addKey()
+	a.enc.appendComplex(val, 64)
+}
+
+func (a *logfmtArrayEncoder) AppendComplex64(val complex64) {
+	a.addKey()
+	a.enc.appendComplex(complex128(val), 32)
+}
+
+func (a *logfmtArrayEncoder) AppendDuration(val time.Duration) {
+	a.addKey()
+	a.enc.appendDuration(val)
+}
+
+func (a *logfmtArrayEncoder) AppendFloat64(val float64) {
+	a.addKey()
+	a.enc.appendFloat(val, 64)
+}
+
+func (a *logfmtArrayEncoder) AppendFloat32(val float32) {
+	a.addKey()
+	a.enc.appendFloat(float64(val), 32)
+}
+
+func (a *logfmtArrayEncoder) AppendInt(val int) { a.AppendInt64(int64(val)) }
+
+func (a *logfmtArrayEncoder) AppendInt64(val int64) {
+	a.addKey()
+	a.enc.buf.AppendInt(val)
+}
+
+func (a *logfmtArrayEncoder) AppendInt32(val int32) { a.AppendInt64(int64(val)) }
+
+func (a *logfmtArrayEncoder) AppendInt16(val int16) { a.AppendInt64(int64(val)) }
+
+func (a *logfmtArrayEncoder) AppendInt8(val int8) { a.AppendInt64(int64(val)) }
+
+func (a *logfmtArrayEncoder) AppendString(val string) {
+	a.addKey()
+	a.enc.appendString(val)
+}
+
+func (a *logfmtArrayEncoder) AppendTime(val time.Time) {
+	a.addKey()
+	a.enc.appendTime(val)
+}
+
+func (a *logfmtArrayEncoder) AppendUint(val uint) { a.AppendUint64(uint64(val)) }
+
+func (a *logfmtArrayEncoder) AppendUint64(val uint64) {
+	a.addKey()
+	a.enc.buf.AppendUint(val)
+}
+
+func (a *logfmtArrayEncoder) AppendUint32(val uint32) { a.AppendUint64(uint64(val)) }
+
+func (a *logfmtArrayEncoder) AppendUint16(val uint16) { a.AppendUint64(uint64(val)) }
+
+func (a *logfmtArrayEncoder) AppendUint8(val uint8) { a.AppendUint64(uint64(val)) }
+
+func (a *logfmtArrayEncoder) AppendUintptr(val uintptr) { a.AppendUint64(uint64(val)) }
+
+// logfmtValueEncoder collects the values appended by the zapcore encoder
+// functions, e.g. EncodeTime and EncodeLevel.
+type logfmtValueEncoder struct {
+	elems []string
+}
+
+func (v *logfmtValueEncoder) append(s string) { v.elems = append(v.elems, s) }
+
+func (v *logfmtValueEncoder) AppendBool(val bool) { v.append(strconv.FormatBool(val)) }
+
+func (v *logfmtValueEncoder) AppendByteString(val []byte) { v.append(string(val)) }
+
+func (v *logfmtValueEncoder) AppendComplex128(val complex128) {
+	v.append(strconv.FormatComplex(val, 'f', -1, 128))
+}
+
+func (v *logfmtValueEncoder) AppendComplex64(val complex64) {
+	v.append(strconv.FormatComplex(complex128(val), 'f', -1, 64))
+}
+
+func (v *logfmtValueEncoder) AppendFloat64(val float64) {
+	v.append(strconv.FormatFloat(val, 'f', -1, 64))
+}
+
+func (v *logfmtValueEncoder) AppendFloat32(val float32) {
+	v.append(strconv.FormatFloat(float64(val), 'f', -1, 32))
+}
+
+func (v *logfmtValueEncoder) AppendInt(val int) { v.AppendInt64(int64(val)) }
+
+func (v *logfmtValueEncoder) AppendInt64(val int64) { v.append(strconv.FormatInt(val, 10)) }
+
+func (v *logfmtValueEncoder) AppendInt32(val int32) { v.AppendInt64(int64(val)) }
+
+func (v *logfmtValueEncoder) AppendInt16(val int16) { v.AppendInt64(int64(val)) }
+
+func (v *logfmtValueEncoder) AppendInt8(val int8) { v.AppendInt64(int64(val)) }
+
+func (v *logfmtValueEncoder) AppendString(val string) { v.append(val) }
+
+func (v *logfmtValueEncoder) AppendUint(val uint) { v.AppendUint64(uint64(val)) }
+
+func (v *logfmtValueEncoder) AppendUint64(val uint64) { v.append(strconv.FormatUint(val, 10)) }
+
+func (v *logfmtValueEncoder) AppendUint32(val uint32) { v.AppendUint64(uint64(val)) }
+
+func (v *logfmtValueEncoder) AppendUint16(val uint16) { v.AppendUint64(uint64(val)) }
+
+func (v *logfmtValueEncoder) AppendUint8(val uint8) { v.AppendUint64(uint64(val)) }
+
+func (v *logfmtValueEncoder) AppendUintptr(val uintptr) { v.AppendUint64(uint64(val)) }
@@ -0,0 +1,90 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+type testUser struct {
+	Name string
+	Tags []string
+}
+
+func (u testUser) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", u.Name)
+	return enc.AddArray("tags", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		for _, tag := range u.Tags {
+			arr.AppendString(tag)
+		}
+		return nil
+	}))
+}
+
+func TestLogfmtEncoder(t *testing.T) {
+	enc := NewLogfmtEncoder(zapcore.EncoderConfig{
+		TimeKey:        "time",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		StacktraceKey:  "stack",
+		EncodeTime:     zapcore.RFC3339TimeEncoder,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+	})
+	enc.AddString("service", "api")
+	ctx := enc.Clone()
+	ctx.OpenNamespace("req")
+	ctx.AddInt("id", 1)
+
+	ent := zapcore.Entry{
+		Level:      InfoLevel,
+		Time:       time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+		LoggerName: "main",
+		Message:    "hello \"world\"",
+		Caller:     zapcore.NewEntryCaller(0, "/src/log/logfmt.go", 10, true),
+	}
+	buf, err := ctx.EncodeEntry(ent, []Field{
+		Object("user", testUser{Name: "foo bar", Tags: []string{"a", "b"}}),
+		Ints("empty", nil),
+		Duration("elapsed", time.Second),
+		Err(errors.New("boom")),
+		Bool("ok key", true),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `time=2006-01-02T15:04:05Z level=info logger=main caller=log/logfmt.go:10 `+
+		`msg="hello \"world\"" service=api req.id=1 req.user.name="foo bar" req.user.tags.0=a `+
+		`req.user.tags.1=b req.empty=[] req.elapsed=1s req.error=boom req.ok_key=true`+"\n", buf.String())
+}
+
+func TestLogfmtFileFormat(t *testing.T) {
+	dir := t.TempDir()
+	opts := NewOptions()
+	opts.DisableConsole = true
+	opts.DisableFile = false
+	opts.DisableFileTime = true
+	opts.FileFormat = FormatLogfmt
+	opts.Output = dir
+	opts.FilenameEncoder = func() string {
+		return "test.log"
+	}
+	l := New(opts)
+	l.Infot("Hello, world!", String("key", "value"))
+	assert.NoError(t, l.Close())
+
+	content, err := os.ReadFile(filepath.Join(dir, "test.log"))
+	assert.NoError(t, err)
+	assert.Equal(t, "level=INFO msg=\"Hello, world!\" key=value\n", string(content))
+
+	opts.FileFormat = "xml"
+	errs := opts.Validate()
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, "unrecognized format: \"xml\"", errs[0].Error())
+}
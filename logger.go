@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -19,19 +20,37 @@ type Logger struct {
 	log             *zap.Logger
 	sugared         *zap.SugaredLogger
 	encodedFilename string
+	// verbosity is the max V-level of the logr loggers, 0 means no limit
+	verbosity int
+	// clock is the Clock of the entry times, in the TimeZone of the Options
+	clock Clock
 }
 
-// New creates a new Logger.
+// New creates a new Logger. It panics if the log file can't be opened, or
+// it's used by another Logger with the different options.
 func New(opts *Options) *Logger {
+	l, err := newLogger(opts)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+func newLogger(opts *Options) (*Logger, error) {
 	l := &Logger{}
+	opts.applyMode()
 	// set a default filename encoder if log file is enabled
-	if !opts.DisableFile && len(opts.Output) > 0 && opts.FilenameEncoder == nil {
-		opts.FilenameEncoder = DefaultFilenameEncoder
+	if !opts.DisableFile && len(opts.Output) > 0 && opts.FilenameEncoder == nil && opts.TimeFilenameEncoder == nil {
+		opts.TimeFilenameEncoder = DailyTimeFilenameEncoder
 	}
 
-	var cores []zapcore.Core
+	var (
+		cores       []zapcore.Core
+		consoleCore zapcore.Core
+	)
 	// set encoders, will override the default encoder if exists
 	encoderConfig := l.getEncoderConfig(opts)
+	clock := opts.clock()
 
 	if !opts.DisableConsole {
 		var consoleLevel Level
@@ -41,29 +60,39 @@ func New(opts *Options) *Logger {
 		}
 		consoleEncCfg := encoderConfig
 		if !opts.DisableConsoleLevel {
-			consoleEncCfg.LevelKey = "level"
+			consoleEncCfg.LevelKey = keyOrDefault(opts.LevelKey, "level")
 		}
 		if !opts.DisableConsoleTime {
-			consoleEncCfg.TimeKey = "time"
+			consoleEncCfg.TimeKey = keyOrDefault(opts.TimeKey, "time")
 		}
 		if !opts.DisableConsoleCaller {
-			consoleEncCfg.CallerKey = "caller"
+			consoleEncCfg.CallerKey = keyOrDefault(opts.CallerKey, "caller")
 		}
 		// forces to use CapitalColorLevelEncoder if LevelEncoder is not set when console color is enabled
-		if !opts.DisableConsoleColor && opts.LevelEncoder == nil {
+		if !opts.DisableConsoleColor && opts.LevelEncoder == nil && opts.LevelFormat == "" &&
+			opts.consoleFormat() == FormatConsole && opts.ConsoleTemplate == "" {
 			consoleEncCfg.EncodeLevel = zapcore.CapitalColorLevelEncoder
 		}
 		consoleLevelEnabler := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
 			return lvl >= consoleLevel
 		})
-		consoleEncoder := zapcore.NewConsoleEncoder(consoleEncCfg)
+		consoleColor := !opts.DisableConsoleColor && isColorTerminal(os.Stdout)
+		consoleEncoder := newEncoder(opts, opts.consoleFormat(), consoleEncCfg, consoleColor)
+		if opts.ConsoleTemplate != "" && opts.consoleFormat() == FormatConsole {
+			// the template is validated by Options.Validate
+			if enc, err := NewTemplateEncoder(consoleEncCfg, opts.ConsoleTemplate, consoleColor); err == nil {
+				consoleEncoder = enc
+			}
+		}
+		consoleEncoder = newLimitEncoder(opts, consoleEncoder)
 
-		cores = append(cores, zapcore.NewCore(consoleEncoder, zapcore.Lock(os.Stdout), consoleLevelEnabler))
+		consoleCore = zapcore.NewCore(consoleEncoder, zapcore.Lock(os.Stdout), consoleLevelEnabler)
+		cores = append(cores, consoleCore)
 	}
 
 	var (
 		syncer          zapcore.WriteSyncer
-		closer          io.Closer
+		closers         multiCloser
 		encodedFilename string
 	)
 	if !opts.DisableFile {
@@ -76,37 +105,77 @@ func New(opts *Options) *Logger {
 			fileLevel = InfoLevel
 		}
 		// Add level key for file log by default
-		encoderConfig.LevelKey = "level"
+		encoderConfig.LevelKey = keyOrDefault(opts.LevelKey, "level")
 		if !opts.DisableFileTime {
-			encoderConfig.TimeKey = "time"
+			encoderConfig.TimeKey = keyOrDefault(opts.TimeKey, "time")
 		}
 		if !opts.DisableFileCaller {
-			encoderConfig.CallerKey = "caller"
-		}
-		fileEncoder := zapcore.NewConsoleEncoder(encoderConfig)
-		if !opts.DisableFileJson {
-			fileEncoder = zapcore.NewJSONEncoder(encoderConfig)
+			encoderConfig.CallerKey = keyOrDefault(opts.CallerKey, "caller")
 		}
+		fileEncoder := newLimitEncoder(opts, newEncoder(opts, opts.fileFormat(), encoderConfig, false))
 
 		fileLevelEnabler := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
 			return lvl >= fileLevel
 		})
-		syncer, closer, encodedFilename = rollingFileEncoder(opts, opts.FilenameEncoder)
-		cores = append(cores, zapcore.NewCore(fileEncoder, syncer, fileLevelEnabler))
+		var (
+			closer io.Closer
+			header func() []byte
+		)
+		if opts.FileHeader {
+			header = fileHeader(opts, fileEncoder)
+		}
+		syncer, closer, encodedFilename, err = rollingFileEncoder(opts, opts.filenameEncoder(clock), header)
+		if err != nil {
+			return nil, err
+		}
+		closers = append(closers, closer)
+		fileCore := zapcore.NewCore(fileEncoder, syncer, fileLevelEnabler)
+		if opts.SyncPolicy == SyncPolicyLevel {
+			var syncLevel Level
+			if err = syncLevel.Set(strings.ToLower(opts.SyncLevel)); err != nil || opts.SyncLevel == "" {
+				syncLevel = ErrorLevel
+			}
+			fileCore = &syncCore{Core: fileCore, syncer: syncer, level: syncLevel}
+		}
+		// limits the file logging when the free disk space is low
+		if opts.MinFreeSpace > 0 {
+			guard := newDiskGuard(opts, consoleCore)
+			guard.start()
+			closers = append(closers, guard)
+			fileCore = &diskGuardCore{Core: fileCore, guard: guard}
+		}
+		cores = append(cores, fileCore)
+	}
+	core := newStackCore(opts, zapcore.NewTee(cores...))
+	if intValue(opts.SamplingInitial) > 0 {
+		tick := opts.SamplingTick
+		if tick <= 0 {
+			tick = time.Second
+		}
+		core = zapcore.NewSamplerWithOptions(core, tick, *opts.SamplingInitial, intValue(opts.SamplingThereafter))
 	}
-	core := zapcore.NewTee(cores...)
 	// zap.WithCaller(true), need set CallerKey, otherwise will not output caller info
 	// zap.AddCallerSkip(1) output the right position of caller
 	if opts.CallerSkip < 0 {
 		opts.CallerSkip = DefaultCallerSkip
 	}
-	unsugared := zap.New(core, zap.WithCaller(true), zap.AddCallerSkip(opts.CallerSkip))
+	zapOpts := []zap.Option{zap.WithCaller(true), zap.AddCallerSkip(opts.CallerSkip), zap.WithClock(clock)}
+	// DPanic panics in development
+	if opts.development() {
+		zapOpts = append(zapOpts, zap.Development())
+	}
+	unsugared := zap.New(core, zapOpts...)
+	if fields := resourceFields(opts); len(fields) > 0 {
+		unsugared = unsugared.With(fields...)
+	}
 	return &Logger{
 		log:             unsugared,
 		sugared:         unsugared.Sugar(),
-		closer:          closer,
+		closer:          closers,
 		encodedFilename: encodedFilename,
-	}
+		verbosity:       opts.Verbosity,
+		clock:           clock,
+	}, nil
 }
 
 func (l *Logger) DebugLogger() DebugLogger {
@@ -126,7 +195,7 @@ func (l *Logger) Debugf(template string, args ...interface{}) {
 }
 
 func (l *Logger) Debug(msg string, keysAndValues ...interface{}) {
-	l.sugared.Debugw(msg, keysAndValues...)
+	l.sugared.Debugw(msg, errorArgs(keysAndValues)...)
 }
 
 func (l *Logger) Infot(msg string, fields ...Field) {
@@ -138,7 +207,7 @@ func (l *Logger) Infof(template string, args ...interface{}) {
 }
 
 func (l *Logger) Info(msg string, keysAndValues ...interface{}) {
-	l.sugared.Infow(msg, keysAndValues...)
+	l.sugared.Infow(msg, errorArgs(keysAndValues)...)
 }
 
 func (l *Logger) Warnt(msg string, fields ...Field) {
@@ -150,7 +219,7 @@ func (l *Logger) Warnf(template string, args ...interface{}) {
 }
 
 func (l *Logger) Warn(msg string, keysAndValues ...interface{}) {
-	l.sugared.Warnw(msg, keysAndValues...)
+	l.sugared.Warnw(msg, errorArgs(keysAndValues)...)
 }
 
 func (l *Logger) Errort(msg string, fields ...Field) {
@@ -162,7 +231,19 @@ func (l *Logger) Errorf(template string, args ...interface{}) {
 }
 
 func (l *Logger) Error(msg string, keysAndValues ...interface{}) {
-	l.sugared.Errorw(msg, keysAndValues...)
+	l.sugared.Errorw(msg, errorArgs(keysAndValues)...)
+}
+
+func (l *Logger) DPanict(msg string, fields ...Field) {
+	l.log.DPanic(msg, fields...)
+}
+
+func (l *Logger) DPanicf(template string, args ...interface{}) {
+	l.sugared.DPanicf(template, args...)
+}
+
+func (l *Logger) DPanic(msg string, keysAndValues ...interface{}) {
+	l.sugared.DPanicw(msg, errorArgs(keysAndValues)...)
 }
 
 func (l *Logger) Panict(msg string, fields ...Field) {
@@ -174,7 +255,7 @@ func (l *Logger) Panicf(template string, args ...interface{}) {
 }
 
 func (l *Logger) Panic(msg string, keysAndValues ...interface{}) {
-	l.sugared.Panicw(msg, keysAndValues...)
+	l.sugared.Panicw(msg, errorArgs(keysAndValues)...)
 }
 
 func (l *Logger) Fatalt(msg string, fields ...Field) {
@@ -186,20 +267,21 @@ func (l *Logger) Fatalf(template string, args ...interface{}) {
 }
 
 func (l *Logger) Fatal(msg string, keysAndValues ...interface{}) {
-	l.sugared.Fatalw(msg, keysAndValues...)
+	l.sugared.Fatalw(msg, errorArgs(keysAndValues)...)
 }
 
-// Print logs a message at level Print.
+// Print logs a message at InfoLevel.
 func (l *Logger) Print(args ...interface{}) {
 	l.log.Info(fmt.Sprint(args...))
 }
 
-// Println logs a message at level Print.
+// Println logs a message at InfoLevel, the operands are separated by
+// spaces like fmt.Println, without the trailing newline.
 func (l *Logger) Println(args ...interface{}) {
-	l.log.Info(fmt.Sprint(args...))
+	l.log.Info(strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
 }
 
-// Printf logs a message at level Print.
+// Printf logs a message at InfoLevel.
 func (l *Logger) Printf(format string, args ...interface{}) {
 	l.log.Info(fmt.Sprintf(format, args...))
 }
@@ -208,6 +290,8 @@ func (l *Logger) AtLevelt(level Level, msg string, fields ...Field) {
 	switch level {
 	case DebugLevel:
 		l.Debugt(msg, fields...)
+	case DPanicLevel:
+		l.DPanict(msg, fields...)
 	case PanicLevel:
 		l.Panict(msg, fields...)
 	case ErrorLevel:
@@ -228,6 +312,8 @@ func (l *Logger) AtLevel(level Level, msg string, keysAndValues ...interface{})
 	switch level {
 	case DebugLevel:
 		l.Debug(msg, keysAndValues...)
+	case DPanicLevel:
+		l.DPanic(msg, keysAndValues...)
 	case PanicLevel:
 		l.Panic(msg, keysAndValues...)
 	case ErrorLevel:
@@ -248,6 +334,8 @@ func (l *Logger) AtLevelf(level Level, msg string, args ...interface{}) {
 	switch level {
 	case DebugLevel:
 		l.Debugf(msg, args...)
+	case DPanicLevel:
+		l.DPanicf(msg, args...)
 	case PanicLevel:
 		l.Panicf(msg, args...)
 	case ErrorLevel:
@@ -278,8 +366,22 @@ func (l *Logger) Sugared() *zap.SugaredLogger {
 func (l *Logger) WithValues(fields ...Field) *Logger {
 	newl := l.log.With(fields...)
 	return &Logger{
-		log:     newl,
-		sugared: newl.Sugar(),
+		log:       newl,
+		sugared:   newl.Sugar(),
+		verbosity: l.verbosity,
+		clock:     l.clock,
+	}
+}
+
+// Named creates a child logger and adds a new path segment to the logger's
+// name. Segments are joined by periods.
+func (l *Logger) Named(name string) *Logger {
+	newl := l.log.Named(name)
+	return &Logger{
+		log:       newl,
+		sugared:   newl.Sugar(),
+		verbosity: l.verbosity,
+		clock:     l.clock,
 	}
 }
 
@@ -290,6 +392,7 @@ func (l *Logger) Flush() error {
 }
 
 // Close implements io.Closer, and closes the current logfile of default logger.
+// The logfile shared with other Loggers is closed when the last one is closed.
 func (l *Logger) Close() error {
 	// https://github.com/uber-go/zap/issues/772
 	_ = l.Flush()
@@ -300,6 +403,12 @@ func (l *Logger) Close() error {
 	return nil
 }
 
+// Enabled reports whether the logger logs the messages at the level, it is
+// a cheap guard of the expensive fields.
+func (l *Logger) Enabled(level Level) bool {
+	return l.log.Core().Enabled(level)
+}
+
 // Check returns a CheckedEntry if logging a message at the specified level
 // is enabled. It's a completely optional optimization; in high-performance
 // applications, Check can help avoid allocating a slice to hold fields.
@@ -311,16 +420,44 @@ func (l *Logger) EncodedFilename() string {
 	return l.encodedFilename
 }
 
+// multiCloser closes all the closers and returns the first error.
+type multiCloser []io.Closer
+
+func (m multiCloser) Close() error {
+	var err error
+	// close in the reverse order of creation
+	for i := len(m) - 1; i >= 0; i-- {
+		if cerr := m[i].Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
 func (l *Logger) getEncoderConfig(opts *Options) zapcore.EncoderConfig {
 	encoderConfig := zapcore.EncoderConfig{
-		NameKey:          "logger",
-		MessageKey:       "msg",
-		StacktraceKey:    "stack",
-		LineEnding:       zapcore.DefaultLineEnding,
+		NameKey:          keyOrDefault(opts.NameKey, "logger"),
+		MessageKey:       keyOrDefault(opts.MessageKey, "msg"),
+		FunctionKey:      opts.FunctionKey,
+		StacktraceKey:    keyOrDefault(opts.StacktraceKey, "stack"),
+		LineEnding:       opts.lineEnding(),
 		EncodeLevel:      zapcore.CapitalLevelEncoder,
 		EncodeDuration:   zapcore.MillisDurationEncoder,
 		EncodeCaller:     zapcore.ShortCallerEncoder,
-		ConsoleSeparator: " ",
+		ConsoleSeparator: keyOrDefault(opts.ConsoleSeparator, " "),
+	}
+	// the named encoders are overridden by the encoder funcs
+	if enc, ok := timeEncoders[opts.TimeFormat]; ok {
+		encoderConfig.EncodeTime = enc
+	}
+	if enc, ok := levelEncoders[opts.LevelFormat]; ok {
+		encoderConfig.EncodeLevel = enc
+	}
+	if enc, ok := callerEncoders[opts.CallerFormat]; ok {
+		encoderConfig.EncodeCaller = enc
+	}
+	if enc, ok := durationEncoders[opts.DurationFormat]; ok {
+		encoderConfig.EncodeDuration = enc
 	}
 	if opts.TimeEncoder != nil {
 		encoderConfig.EncodeTime = opts.TimeEncoder
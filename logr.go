@@ -0,0 +1,118 @@
+package log
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+)
+
+// logrSink is a logr.LogSink which writes through a Logger.
+type logrSink struct {
+	l *Logger
+	// depth is the frames of logr skipped by the caller annotation
+	depth int
+	log   *zap.Logger
+}
+
+// NewLogr creates a logr.Logger which writes through the logger, see
+// NewLogrSink.
+func NewLogr(l *Logger) logr.Logger {
+	return logr.New(NewLogrSink(l))
+}
+
+// NewLogrSink creates a logr.LogSink which writes through the logger. V(0)
+// is logged at InfoLevel and the higher V-levels at DebugLevel, the V-levels
+// above the Verbosity of the Options are disabled. Error is logged at
+// ErrorLevel with the error encoded by Err, WithName and WithValues create
+// the child loggers by Named and WithValues.
+func NewLogrSink(l *Logger) logr.LogSink {
+	return &logrSink{l: l, log: l.log}
+}
+
+func (s *logrSink) Init(info logr.RuntimeInfo) {
+	s.depth = info.CallDepth
+	s.log = s.l.log.WithOptions(zap.AddCallerSkip(s.depth))
+}
+
+func (s *logrSink) Enabled(level int) bool {
+	if s.l.verbosity > 0 && level > s.l.verbosity {
+		return false
+	}
+	return s.log.Core().Enabled(logrLevel(level))
+}
+
+func (s *logrSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	if ce := s.log.Check(logrLevel(level), msg); ce != nil {
+		ce.Write(logrFields(keysAndValues)...)
+	}
+}
+
+func (s *logrSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	if ce := s.log.Check(ErrorLevel, msg); ce != nil {
+		ce.Write(append([]Field{Err(err)}, logrFields(keysAndValues)...)...)
+	}
+}
+
+func (s *logrSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return s.with(s.l.WithValues(logrFields(keysAndValues)...))
+}
+
+func (s *logrSink) WithName(name string) logr.LogSink {
+	return s.with(s.l.Named(name))
+}
+
+func (s *logrSink) WithCallDepth(depth int) logr.LogSink {
+	clone := *s
+	clone.depth += depth
+	clone.log = s.l.log.WithOptions(zap.AddCallerSkip(clone.depth))
+	return &clone
+}
+
+// with returns a sink of the child logger with the same call depth.
+func (s *logrSink) with(l *Logger) logr.LogSink {
+	clone := *s
+	clone.l = l
+	clone.log = l.log.WithOptions(zap.AddCallerSkip(s.depth))
+	return &clone
+}
+
+// logrLevel maps the V-level to the level.
+func logrLevel(level int) Level {
+	if level <= 0 {
+		return InfoLevel
+	}
+	return DebugLevel
+}
+
+// logrFields converts the keysAndValues of logr to the fields, the values
+// implementing logr.Marshaler are replaced by their MarshalLog, the errors
+// are encoded by NamedErr. A missing value is logged as "(MISSING)".
+func logrFields(keysAndValues []interface{}) []Field {
+	fields := make([]Field, 0, (len(keysAndValues)+1)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if f, ok := keysAndValues[i].(Field); ok {
+			fields = append(fields, f)
+			i--
+			continue
+		}
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+		if i+1 == len(keysAndValues) {
+			fields = append(fields, String(key, "(MISSING)"))
+			break
+		}
+		val := keysAndValues[i+1]
+		if m, ok := val.(logr.Marshaler); ok {
+			val = m.MarshalLog()
+		}
+		if err, ok := val.(error); ok {
+			fields = append(fields, NamedErr(key, err))
+			continue
+		}
+		fields = append(fields, Any(key, val))
+	}
+	return fields
+}
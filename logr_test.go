@@ -0,0 +1,61 @@
+package log
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+)
+
+type testLogrMarshaler struct{ id int }
+
+func (m testLogrMarshaler) MarshalLog() interface{} {
+	return map[string]int{"id": m.id}
+}
+
+func logrHelper(l logr.Logger, msg string) {
+	l.WithCallDepth(1).Info(msg)
+}
+
+func TestLogrSink(t *testing.T) {
+	l := newContextTestLogger(t)
+	lr := NewLogr(l)
+
+	lr.Info("info", "user", "alice", "attempt", 2)
+	lr.V(1).Info("debug", "obj", testLogrMarshaler{id: 7})
+	lr.WithName("controller").WithValues("kind", "Pod").WithName("reconciler").Info("named")
+	lr.Error(errors.New("boom"), "failed", "cause", errors.New("timeout"), "odd")
+	logrHelper(lr.WithValues("helper", true), "helper")
+
+	lines := readContextTestLogger(t, l)
+	assert.Equal(t, []string{
+		`{"level":"INFO","caller":"log/logr_test.go:25","msg":"info","user":"alice","attempt":2}`,
+		`{"level":"DEBUG","caller":"log/logr_test.go:26","msg":"debug","obj":{"id":7}}`,
+		`{"level":"INFO","logger":"controller.reconciler","caller":"log/logr_test.go:27","msg":"named","kind":"Pod"}`,
+		`{"level":"ERROR","caller":"log/logr_test.go:28","msg":"failed","error":"boom",` +
+			`"cause":"timeout","odd":"(MISSING)"}`,
+		`{"level":"INFO","caller":"log/logr_test.go:29","msg":"helper","helper":true}`,
+	}, lines)
+}
+
+func TestLogrVerbosity(t *testing.T) {
+	opts := NewOptions()
+	opts.ConsoleLevel = DebugLevel.String()
+	opts.Verbosity = 2
+	lr := NewLogr(New(opts))
+	assert.True(t, lr.V(0).Enabled())
+	assert.True(t, lr.V(2).Enabled())
+	assert.False(t, lr.V(3).Enabled())
+	assert.False(t, lr.WithName("child").V(3).Enabled())
+
+	opts = NewOptions()
+	lr = NewLogr(New(opts))
+	assert.True(t, lr.Enabled())
+	assert.False(t, lr.V(1).Enabled())
+
+	opts.Verbosity = -1
+	errs := opts.Validate()
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, "negative verbosity: -1", errs[0].Error())
+}
@@ -3,8 +3,22 @@ package log
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/spf13/pflag"
+	"go.uber.org/zap/zapcore"
+)
+
+// Modes of the Options.
+const (
+	// ModeDevelopment is the mode of NewDevelopmentOptions, in which DPanic
+	// panics.
+	ModeDevelopment = "development"
+	// ModeProduction is the mode of NewProductionOptions.
+	ModeProduction = "production"
 )
 
 // Options Configuration for logging.
@@ -19,6 +33,21 @@ type Options struct {
 	DisableConsoleLevel bool `json:"disable-console-level" mapstructure:"disable-console-level"`
 	// DisableConsoleCaller whether to log caller info
 	DisableConsoleCaller bool `json:"disable-console-caller" mapstructure:"disable-console-caller"`
+	// ConsoleFormat the console log format, one of console, json, logfmt, ecs, gcp and dev, default console
+	ConsoleFormat string `json:"console-format" mapstructure:"console-format"`
+	// ConsoleTemplate the layout of the console lines in the console format,
+	// e.g. {time:15:04:05} {level:5} {? [{logger}]} {caller} {msg} {fields}.
+	// See NewTemplateEncoder for the syntax.
+	ConsoleTemplate string `json:"console-template" mapstructure:"console-template"`
+	// ConsoleRelativeTime whether to log the elapsed time since the start
+	// instead of the time in the dev format
+	ConsoleRelativeTime bool `json:"console-relative-time" mapstructure:"console-relative-time"`
+	// ConsoleMultilineFields whether to log every field on an indented
+	// continuation line in the dev format
+	ConsoleMultilineFields bool `json:"console-multiline-fields" mapstructure:"console-multiline-fields"`
+	// ConsoleHyperlinks whether to log the callers as terminal hyperlinks in
+	// the dev format, it only takes effect when the colors are enabled
+	ConsoleHyperlinks bool `json:"console-hyperlinks" mapstructure:"console-hyperlinks"`
 
 	// DisableFile whether to log to file
 	DisableFile bool `json:"disable-file" mapstructure:"disable-file"`
@@ -29,6 +58,53 @@ type Options struct {
 	DisableFileTime bool `json:"disable-file-time" mapstructure:"disable-file-time"`
 	// DisableFileCaller whether to log caller info
 	DisableFileCaller bool `json:"disable-file-caller" mapstructure:"disable-file-caller"`
+	// FileFormat the file log format, one of console, json, logfmt, ecs, gcp and dev, it
+	// overrides DisableFileJson. Default json, or console if DisableFileJson is set.
+	FileFormat string `json:"file-format" mapstructure:"file-format"`
+	// ECSNamespace the key under which the fields are placed in the ecs format,
+	// the fields are placed at the top level if it is empty
+	ECSNamespace string `json:"ecs-namespace" mapstructure:"ecs-namespace"`
+	// GCPProjectID the Google Cloud project ID, the trace of the gcp format is
+	// formatted as projects/<GCPProjectID>/traces/<trace_id> if it is not empty
+	GCPProjectID string `json:"gcp-project-id" mapstructure:"gcp-project-id"`
+	// GCPSplitTimestamp whether to encode the time of the gcp format as
+	// timestampSeconds and timestampNanos instead of timestamp
+	GCPSplitTimestamp bool `json:"gcp-split-timestamp" mapstructure:"gcp-split-timestamp"`
+
+	// Service the name of the service, it is added to every entry as the
+	// service field if it is not empty
+	Service string `json:"service" mapstructure:"service"`
+	// Version the version of the service, it is added to every entry as the
+	// version field if it is not empty
+	Version string `json:"version" mapstructure:"version"`
+	// Environment the deployment environment of the service, e.g. production,
+	// it is added to every entry as the environment field if it is not empty
+	Environment string `json:"environment" mapstructure:"environment"`
+	// DetectResource whether to detect the hostname, the pid, the container
+	// id and the pod metadata, and add them to every entry
+	DetectResource bool `json:"detect-resource" mapstructure:"detect-resource"`
+	// DisableHostname whether to skip the detected hostname
+	DisableHostname bool `json:"disable-hostname" mapstructure:"disable-hostname"`
+	// DisablePID whether to skip the detected pid
+	DisablePID bool `json:"disable-pid" mapstructure:"disable-pid"`
+	// DisableContainerID whether to skip the container id detected from the
+	// cgroups
+	DisableContainerID bool `json:"disable-container-id" mapstructure:"disable-container-id"`
+	// DisablePodMetadata whether to skip the pod name, the pod namespace and
+	// the node name detected from the downward API env vars
+	DisablePodMetadata bool `json:"disable-pod-metadata" mapstructure:"disable-pod-metadata"`
+	// ResourceNamespace the key under which the resource fields are placed,
+	// the fields are placed at the top level if it is empty
+	ResourceNamespace string `json:"resource-namespace" mapstructure:"resource-namespace"`
+
+	// FileHeader whether to write a header entry with the build and process
+	// metadata at the start of every new log file
+	FileHeader bool `json:"file-header" mapstructure:"file-header"`
+
+	// SharedFile whether the log file is shared by multiple processes, the
+	// rotation is coordinated by a lock file so that exactly one process
+	// rotates the log file. It is only supported on unix-like systems.
+	SharedFile bool `json:"shared-file" mapstructure:"shared-file"`
 
 	// DisableRotate whether to enable log file rotate
 	DisableRotate bool `json:"disable-rotate" mapstructure:"disable-rotate"`
@@ -38,6 +114,95 @@ type Options struct {
 	MaxBackups int `json:"max-backups" mapstructure:"max-backups"`
 	// MaxAge the max age in days to keep a logfile
 	MaxAge int `json:"max-age" mapstructure:"max-age"`
+	// Compress whether to compress the rotated logfiles with gzip
+	Compress bool `json:"compress" mapstructure:"compress"`
+
+	// SyncPolicy sets when the log file is committed to disk, one of
+	// none, write, interval and level, default none
+	SyncPolicy string `json:"sync-policy" mapstructure:"sync-policy"`
+	// SyncInterval the interval of the interval sync policy, default 1s
+	SyncInterval time.Duration `json:"sync-interval" mapstructure:"sync-interval"`
+	// SyncLevel the min level of the level sync policy, default error
+	SyncLevel string `json:"sync-level" mapstructure:"sync-level"`
+	// BufferSize the size in bytes of the write buffer of the log file,
+	// 0 disables the buffer
+	BufferSize int `json:"buffer-size" mapstructure:"buffer-size"`
+	// FlushInterval the interval of flushing the write buffer, default 1s
+	FlushInterval time.Duration `json:"flush-interval" mapstructure:"flush-interval"`
+
+	// FileMode the permission of the log files in octal, e.g. "0640", default 0644
+	FileMode FileMode `json:"file-mode" mapstructure:"file-mode"`
+	// DirMode the permission of the created log directories in octal, e.g. "0750", default 0755
+	DirMode FileMode `json:"dir-mode" mapstructure:"dir-mode"`
+	// FileUID the owner uid of the log files and the created log directories
+	FileUID *int `json:"file-uid,omitempty" mapstructure:"file-uid"`
+	// FileGID the owner gid of the log files and the created log directories
+	FileGID *int `json:"file-gid,omitempty" mapstructure:"file-gid"`
+
+	// MinFreeSpace the min free space in MB of the output directory, below
+	// which the file logging is limited to LowSpaceLevel. 0 disables the check.
+	MinFreeSpace int `json:"min-free-space" mapstructure:"min-free-space"`
+	// LowSpaceLevel sets the file logger level when the free space is low,
+	// the file logging is stopped if it is empty.
+	LowSpaceLevel string `json:"low-space-level" mapstructure:"low-space-level"`
+	// DiskCheckInterval the interval of the free space check, default 10s
+	DiskCheckInterval time.Duration `json:"disk-check-interval" mapstructure:"disk-check-interval"`
+
+	// TimeZone the time zone of the entry times, the filenames and the
+	// rotation, one of UTC, Local or an IANA time zone name, e.g.
+	// America/New_York. Default the local time zone.
+	TimeZone string `json:"time-zone" mapstructure:"time-zone"`
+
+	// MaxMessageBytes the max size in bytes of the messages, the longer
+	// messages are truncated with a marker. 0 means no limit.
+	MaxMessageBytes int `json:"max-message-bytes" mapstructure:"max-message-bytes"`
+	// MaxFieldBytes the max size in bytes of the string and byte fields, the
+	// longer fields are truncated with a marker. 0 means no limit.
+	MaxFieldBytes int `json:"max-field-bytes" mapstructure:"max-field-bytes"`
+	// MaxEntryBytes the max size in bytes of the encoded entries, the message
+	// of a larger entry is truncated and its fields are dropped if they do not
+	// fit. 0 means no limit.
+	MaxEntryBytes int `json:"max-entry-bytes" mapstructure:"max-entry-bytes"`
+	// SplitEntries whether to split the entries larger than MaxEntryBytes into
+	// continuation entries sharing an entry_id instead of truncating them
+	SplitEntries bool `json:"split-entries" mapstructure:"split-entries"`
+
+	// StacktraceLevel the min level of the entries with a stacktrace, the
+	// stacktraces are not captured if it is empty
+	StacktraceLevel string `json:"stacktrace-level" mapstructure:"stacktrace-level"`
+	// StacktraceMode the frames of the stacktraces, one of full and trimmed,
+	// which skips the frames of this package, zap and the runtime. Default full
+	StacktraceMode string `json:"stacktrace-mode" mapstructure:"stacktrace-mode"`
+	// MaxStackFrames the max frames of the stacktraces, 0 means no limit
+	MaxStackFrames int `json:"max-stack-frames" mapstructure:"max-stack-frames"`
+
+	// Mode the mode of the logger, one of development and production. New
+	// applies the preset of the mode, see NewDevelopmentOptions and
+	// NewProductionOptions, to the options which are not set: the empty
+	// formats and levels, and the nil SamplingInitial and SamplingThereafter.
+	// The development mode implies Development, the production mode enables
+	// the rotated log files if the Output is set. MaxBackups, MaxAge and
+	// Compress of the production preset are only set by NewProductionOptions,
+	// their zero values are valid settings.
+	Mode string `json:"mode" mapstructure:"mode"`
+	// Development whether the logger is in development mode, which makes
+	// DPanic panic
+	Development bool `json:"development" mapstructure:"development"`
+
+	// SamplingInitial the number of the entries with the same level and
+	// message logged in every SamplingTick before the sampling starts,
+	// 0 disables the sampling, nil is the preset of the Mode or 0
+	SamplingInitial *int `json:"sampling-initial,omitempty" mapstructure:"sampling-initial"`
+	// SamplingThereafter the sampling rate after SamplingInitial, every
+	// SamplingThereafter-th entry is logged, 0 drops all of them, nil is
+	// the preset of the Mode or 0
+	SamplingThereafter *int `json:"sampling-thereafter,omitempty" mapstructure:"sampling-thereafter"`
+	// SamplingTick the interval of the sampling, default 1s
+	SamplingTick time.Duration `json:"sampling-tick" mapstructure:"sampling-tick"`
+
+	// Verbosity the max V-level of the logr loggers, V(0) is logged at
+	// InfoLevel and the higher V-levels at DebugLevel. 0 means no limit.
+	Verbosity int `json:"verbosity" mapstructure:"verbosity"`
 
 	// CallerSkip increases the number of callers skipped by caller annotation
 	CallerSkip int `json:"caller-skip" mapstructure:"caller-skip"`
@@ -52,12 +217,63 @@ type Options struct {
 
 	// FilenameEncoder is used to set the log filename encoder.
 	FilenameEncoder FilenameEncoder `json:"-" mapstructure:"-"`
+	// TimeFilenameEncoder is used to set the log filename encoder of the
+	// time of the Clock in the TimeZone, it takes precedence over
+	// FilenameEncoder, default DailyTimeFilenameEncoder.
+	TimeFilenameEncoder TimeFilenameEncoder `json:"-" mapstructure:"-"`
 	// TimeEncoder is used to set the log time encoder.
 	TimeEncoder TimeEncoder `json:"-" mapstructure:"-"`
 	// LevelEncoder is used to set the log level encoder.
 	LevelEncoder LevelEncoder `json:"-" mapstructure:"-"`
 	// CallerEncoder is used to set the log caller encoder.
 	CallerEncoder CallerEncoder `json:"-" mapstructure:"-"`
+	// Clock is used to set the source of the entry times, the filename times
+	// and the rotation times, default the system clock.
+	Clock Clock `json:"-" mapstructure:"-"`
+
+	// MessageKey the key of the message, default msg
+	MessageKey string `json:"message-key" mapstructure:"message-key"`
+	// LevelKey the key of the level, default level
+	LevelKey string `json:"level-key" mapstructure:"level-key"`
+	// TimeKey the key of the time, default time
+	TimeKey string `json:"time-key" mapstructure:"time-key"`
+	// NameKey the key of the logger name, default logger
+	NameKey string `json:"name-key" mapstructure:"name-key"`
+	// CallerKey the key of the caller, default caller
+	CallerKey string `json:"caller-key" mapstructure:"caller-key"`
+	// FunctionKey the key of the caller function, the function is not logged
+	// if it is empty
+	FunctionKey string `json:"function-key" mapstructure:"function-key"`
+	// StacktraceKey the key of the stacktrace, default stack
+	StacktraceKey string `json:"stacktrace-key" mapstructure:"stacktrace-key"`
+	// ConsoleSeparator the separator of the elements in the console format,
+	// default a space
+	ConsoleSeparator string `json:"console-separator" mapstructure:"console-separator"`
+	// LineEnding the line ending of the entries, the escape sequences are
+	// interpreted, e.g. \r\n. Default \n
+	LineEnding string `json:"line-ending" mapstructure:"line-ending"`
+	// TimeFormat the name of the time encoder, one of default, rfc3339,
+	// rfc3339nano, iso8601, epoch, epoch-millis and epoch-nanos. TimeEncoder
+	// takes precedence over it.
+	TimeFormat string `json:"time-format" mapstructure:"time-format"`
+	// LevelFormat the name of the level encoder, one of capital,
+	// capital-color, lowercase and lowercase-color. LevelEncoder takes
+	// precedence over it.
+	LevelFormat string `json:"level-format" mapstructure:"level-format"`
+	// CallerFormat the name of the caller encoder, one of short and
+	// full-path. CallerEncoder takes precedence over it.
+	CallerFormat string `json:"caller-format" mapstructure:"caller-format"`
+	// DurationFormat the name of the duration encoder, one of string,
+	// seconds, millis and nanos. Default millis
+	DurationFormat string `json:"duration-format" mapstructure:"duration-format"`
+
+	// OnRotate is called after a log file has been rotated and closed, oldname is
+	// the path of the closed file, newname is the path of the file being written.
+	// It is called from a background goroutine.
+	OnRotate func(oldname, newname string) `json:"-" mapstructure:"-"`
+	// OnRemove is called after a rotated log file has been removed because of
+	// MaxBackups or MaxAge. It is called from a background goroutine.
+	OnRemove func(name string) `json:"-" mapstructure:"-"`
 }
 
 // NewOptions creates an Options with default parameters.
@@ -72,6 +288,50 @@ func NewOptions() *Options {
 	}
 }
 
+// NewDevelopmentOptions creates an Options for development, which logs the
+// debug entries to the console in the dev format with the callers, captures
+// the stacktraces at WarnLevel, and panics on DPanic and the invalid
+// key-value pairs of the sugared logging.
+func NewDevelopmentOptions() *Options {
+	return &Options{
+		Mode:            ModeDevelopment,
+		Development:     true,
+		DisableFile:     true,
+		DisableRotate:   true,
+		ConsoleFormat:   FormatDev,
+		ConsoleLevel:    DebugLevel.String(),
+		FileLevel:       DebugLevel.String(),
+		StacktraceLevel: WarnLevel.String(),
+		CallerSkip:      DefaultCallerSkip,
+	}
+}
+
+// NewProductionOptions creates an Options for production, which logs the
+// info entries in JSON with the callers, samples the repeated entries, and
+// captures the stacktraces at ErrorLevel. It logs to stdout until the
+// Output is set, there is no default log directory, the production Mode
+// enables the log files in the Output, they are rotated by size and the
+// old ones are compressed and removed.
+func NewProductionOptions() *Options {
+	return &Options{
+		Mode:                ModeProduction,
+		DisableConsoleColor: true,
+		ConsoleFormat:       FormatJSON,
+		ConsoleLevel:        InfoLevel.String(),
+		DisableFile:         true,
+		FileLevel:           InfoLevel.String(),
+		MaxSize:             defaultMaxSize,
+		MaxBackups:          10,
+		MaxAge:              30,
+		Compress:            true,
+		StacktraceLevel:     ErrorLevel.String(),
+		SamplingInitial:     intPtr(100),
+		SamplingThereafter:  intPtr(100),
+		SamplingTick:        time.Second,
+		CallerSkip:          DefaultCallerSkip,
+	}
+}
+
 // AddFlags adds flags related to logger to the specified FlagSet.
 func (o *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.ConsoleLevel, "log.console-level", o.ConsoleLevel,
@@ -95,18 +355,78 @@ func (o *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.BoolVar(&o.DisableConsoleCaller, "log.disable-console-caller", o.DisableConsoleCaller,
 		"Whether to add caller info.")
 
+	fs.StringVar(&o.ConsoleFormat, "log.console-format", o.ConsoleFormat,
+		"Sets the console log format, one of console, json, logfmt, ecs, gcp and dev.")
+
+	fs.StringVar(&o.ConsoleTemplate, "log.console-template", o.ConsoleTemplate,
+		"Sets the layout of the console lines in the console format, e.g. {time:15:04:05} {level:5} {msg} {fields}.")
+
+	fs.BoolVar(&o.ConsoleRelativeTime, "log.console-relative-time", o.ConsoleRelativeTime,
+		"Whether to log the elapsed time since the start in the dev format.")
+
+	fs.BoolVar(&o.ConsoleMultilineFields, "log.console-multiline-fields", o.ConsoleMultilineFields,
+		"Whether to log every field on an indented continuation line in the dev format.")
+
+	fs.BoolVar(&o.ConsoleHyperlinks, "log.console-hyperlinks", o.ConsoleHyperlinks,
+		"Whether to log the callers as terminal hyperlinks in the dev format.")
+
 	fs.BoolVar(&o.DisableFile, "log.disable-file", o.DisableFile,
 		"Whether to log to file.")
 
 	fs.BoolVar(&o.DisableFileJson, "log.disable-file-json", o.DisableFileJson,
 		"Whether to enable json format for log file.")
 
+	fs.StringVar(&o.FileFormat, "log.file-format", o.FileFormat,
+		"Sets the file log format, one of console, json, logfmt, ecs, gcp and dev, it overrides disable-file-json.")
+
+	fs.StringVar(&o.ECSNamespace, "log.ecs-namespace", o.ECSNamespace,
+		"Sets the key under which the fields are placed in the ecs format.")
+
+	fs.StringVar(&o.GCPProjectID, "log.gcp-project-id", o.GCPProjectID,
+		"Sets the Google Cloud project ID of the trace in the gcp format.")
+
+	fs.BoolVar(&o.GCPSplitTimestamp, "log.gcp-split-timestamp", o.GCPSplitTimestamp,
+		"Whether to encode the time as timestampSeconds and timestampNanos in the gcp format.")
+
+	fs.StringVar(&o.Service, "log.service", o.Service,
+		"Sets the name of the service added to every entry.")
+
+	fs.StringVar(&o.Version, "log.version", o.Version,
+		"Sets the version of the service added to every entry.")
+
+	fs.StringVar(&o.Environment, "log.environment", o.Environment,
+		"Sets the deployment environment of the service added to every entry.")
+
+	fs.BoolVar(&o.DetectResource, "log.detect-resource", o.DetectResource,
+		"Whether to detect the hostname, the pid, the container id and the pod metadata, and add them to every entry.")
+
+	fs.BoolVar(&o.DisableHostname, "log.disable-hostname", o.DisableHostname,
+		"Whether to skip the detected hostname.")
+
+	fs.BoolVar(&o.DisablePID, "log.disable-pid", o.DisablePID,
+		"Whether to skip the detected pid.")
+
+	fs.BoolVar(&o.DisableContainerID, "log.disable-container-id", o.DisableContainerID,
+		"Whether to skip the detected container id.")
+
+	fs.BoolVar(&o.DisablePodMetadata, "log.disable-pod-metadata", o.DisablePodMetadata,
+		"Whether to skip the detected pod name, pod namespace and node name.")
+
+	fs.StringVar(&o.ResourceNamespace, "log.resource-namespace", o.ResourceNamespace,
+		"Sets the key under which the resource fields are placed.")
+
 	fs.BoolVar(&o.DisableFileTime, "log.disable-file-time", o.DisableFileTime,
 		"Whether to add a time.")
 
 	fs.BoolVar(&o.DisableFileCaller, "log.disable-file-caller", o.DisableFileCaller,
 		"Whether to add caller info.")
 
+	fs.BoolVar(&o.FileHeader, "log.file-header", o.FileHeader,
+		"Whether to write a header entry with the build and process metadata at the start of every new log file.")
+
+	fs.BoolVar(&o.SharedFile, "log.shared-file", o.SharedFile,
+		"Whether the log file is shared by multiple processes.")
+
 	fs.BoolVar(&o.DisableRotate, "log.disable-rotate", o.DisableRotate,
 		"Whether to enable log file rotate.")
 
@@ -119,8 +439,128 @@ func (o *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.IntVar(&o.MaxAge, "log.max-age", o.MaxAge,
 		"Sets the max age in days to keep a logfile.")
 
+	fs.BoolVar(&o.Compress, "log.compress", o.Compress,
+		"Whether to compress the rotated logfiles with gzip.")
+
+	fs.StringVar(&o.SyncPolicy, "log.sync-policy", o.SyncPolicy,
+		"Sets when the log file is committed to disk, one of none, write, interval and level.")
+
+	fs.DurationVar(&o.SyncInterval, "log.sync-interval", o.SyncInterval,
+		"Sets the interval of the interval sync policy.")
+
+	fs.StringVar(&o.SyncLevel, "log.sync-level", o.SyncLevel,
+		"Sets the min level of the level sync policy.")
+
+	fs.IntVar(&o.BufferSize, "log.buffer-size", o.BufferSize,
+		"Sets the size in bytes of the write buffer of the log file, 0 disables the buffer.")
+
+	fs.DurationVar(&o.FlushInterval, "log.flush-interval", o.FlushInterval,
+		"Sets the interval of flushing the write buffer.")
+
+	fs.Var(&o.FileMode, "log.file-mode",
+		"Sets the permission of the log files in octal, default 0644.")
+
+	fs.Var(&o.DirMode, "log.dir-mode",
+		"Sets the permission of the created log directories in octal, default 0755.")
+
+	fs.Var(newOptionalIntValue(&o.FileUID), "log.file-uid",
+		"Sets the owner uid of the log files and the created log directories.")
+
+	fs.Var(newOptionalIntValue(&o.FileGID), "log.file-gid",
+		"Sets the owner gid of the log files and the created log directories.")
+
+	fs.IntVar(&o.MinFreeSpace, "log.min-free-space", o.MinFreeSpace,
+		"Sets the min free space in MB of the output directory, below which the file logging is limited.")
+
+	fs.StringVar(&o.LowSpaceLevel, "log.low-space-level", o.LowSpaceLevel,
+		"Sets the file logger level when the free space is low, the file logging is stopped if it is empty.")
+
+	fs.DurationVar(&o.DiskCheckInterval, "log.disk-check-interval", o.DiskCheckInterval,
+		"Sets the interval of the free space check.")
+
 	fs.StringVar(&o.Output, "log.output", o.Output,
 		"Sets the directory for logging when DisableFile is false.")
+
+	fs.StringVar(&o.TimeZone, "log.time-zone", o.TimeZone,
+		"Sets the time zone of the entry times, the filenames and the rotation, one of UTC, Local or an IANA name.")
+
+	fs.IntVar(&o.MaxMessageBytes, "log.max-message-bytes", o.MaxMessageBytes,
+		"Sets the max size in bytes of the messages, the longer messages are truncated.")
+
+	fs.IntVar(&o.MaxFieldBytes, "log.max-field-bytes", o.MaxFieldBytes,
+		"Sets the max size in bytes of the string and byte fields, the longer fields are truncated.")
+
+	fs.IntVar(&o.MaxEntryBytes, "log.max-entry-bytes", o.MaxEntryBytes,
+		"Sets the max size in bytes of the encoded entries, the larger entries are truncated.")
+
+	fs.BoolVar(&o.SplitEntries, "log.split-entries", o.SplitEntries,
+		"Whether to split the entries larger than max-entry-bytes into continuation entries.")
+
+	fs.StringVar(&o.StacktraceLevel, "log.stacktrace-level", o.StacktraceLevel,
+		"Sets the min level of the entries with a stacktrace, the stacktraces are not captured if it is empty.")
+
+	fs.StringVar(&o.StacktraceMode, "log.stacktrace-mode", o.StacktraceMode,
+		"Sets the frames of the stacktraces, one of full and trimmed.")
+
+	fs.IntVar(&o.MaxStackFrames, "log.max-stack-frames", o.MaxStackFrames,
+		"Sets the max frames of the stacktraces, 0 means no limit.")
+
+	fs.StringVar(&o.Mode, "log.mode", o.Mode,
+		"Sets the mode of the logger, one of development and production.")
+
+	fs.Var(newOptionalIntValue(&o.SamplingInitial), "log.sampling-initial",
+		"Sets the number of the entries with the same level and message logged in every sampling tick, 0 disables the sampling.")
+
+	fs.Var(newOptionalIntValue(&o.SamplingThereafter), "log.sampling-thereafter",
+		"Sets the sampling rate after sampling-initial, every Nth entry is logged.")
+
+	fs.DurationVar(&o.SamplingTick, "log.sampling-tick", o.SamplingTick,
+		"Sets the interval of the sampling.")
+
+	fs.BoolVar(&o.Development, "log.development", o.Development,
+		"Whether the logger is in development mode, which makes DPanic panic.")
+
+	fs.IntVar(&o.Verbosity, "log.verbosity", o.Verbosity,
+		"Sets the max V-level of the logr loggers, 0 means no limit.")
+
+	fs.StringVar(&o.MessageKey, "log.message-key", o.MessageKey,
+		"Sets the key of the message, default msg.")
+
+	fs.StringVar(&o.LevelKey, "log.level-key", o.LevelKey,
+		"Sets the key of the level, default level.")
+
+	fs.StringVar(&o.TimeKey, "log.time-key", o.TimeKey,
+		"Sets the key of the time, default time.")
+
+	fs.StringVar(&o.NameKey, "log.name-key", o.NameKey,
+		"Sets the key of the logger name, default logger.")
+
+	fs.StringVar(&o.CallerKey, "log.caller-key", o.CallerKey,
+		"Sets the key of the caller, default caller.")
+
+	fs.StringVar(&o.FunctionKey, "log.function-key", o.FunctionKey,
+		"Sets the key of the caller function, the function is not logged if it is empty.")
+
+	fs.StringVar(&o.StacktraceKey, "log.stacktrace-key", o.StacktraceKey,
+		"Sets the key of the stacktrace, default stack.")
+
+	fs.StringVar(&o.ConsoleSeparator, "log.console-separator", o.ConsoleSeparator,
+		"Sets the separator of the elements in the console format, default a space.")
+
+	fs.StringVar(&o.LineEnding, "log.line-ending", o.LineEnding,
+		"Sets the line ending of the entries, the escape sequences are interpreted, default \\n.")
+
+	fs.StringVar(&o.TimeFormat, "log.time-format", o.TimeFormat,
+		"Sets the time encoder, one of default, rfc3339, rfc3339nano, iso8601, epoch, epoch-millis and epoch-nanos.")
+
+	fs.StringVar(&o.LevelFormat, "log.level-format", o.LevelFormat,
+		"Sets the level encoder, one of capital, capital-color, lowercase and lowercase-color.")
+
+	fs.StringVar(&o.CallerFormat, "log.caller-format", o.CallerFormat,
+		"Sets the caller encoder, one of short and full-path.")
+
+	fs.StringVar(&o.DurationFormat, "log.duration-format", o.DurationFormat,
+		"Sets the duration encoder, one of string, seconds, millis and nanos.")
 }
 
 // Validate validates the options fields.
@@ -140,6 +580,85 @@ func (o *Options) Validate() []error {
 		}
 	}
 
+	for _, format := range []string{o.ConsoleFormat, o.FileFormat} {
+		if format != "" && !isValidFormat(format) {
+			errs = append(errs, fmt.Errorf("unrecognized format: %q", format))
+		}
+	}
+
+	if o.ConsoleTemplate != "" {
+		if _, err := parseTemplate(o.ConsoleTemplate); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if o.TimeZone != "" {
+		if _, err := time.LoadLocation(o.TimeZone); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if _, ok := timeEncoders[o.TimeFormat]; o.TimeFormat != "" && !ok {
+		errs = append(errs, fmt.Errorf("unrecognized time format: %q", o.TimeFormat))
+	}
+	if _, ok := levelEncoders[o.LevelFormat]; o.LevelFormat != "" && !ok {
+		errs = append(errs, fmt.Errorf("unrecognized level format: %q", o.LevelFormat))
+	}
+	if _, ok := callerEncoders[o.CallerFormat]; o.CallerFormat != "" && !ok {
+		errs = append(errs, fmt.Errorf("unrecognized caller format: %q", o.CallerFormat))
+	}
+	if _, ok := durationEncoders[o.DurationFormat]; o.DurationFormat != "" && !ok {
+		errs = append(errs, fmt.Errorf("unrecognized duration format: %q", o.DurationFormat))
+	}
+
+	switch o.SyncPolicy {
+	case "", SyncPolicyNone, SyncPolicyWrite, SyncPolicyInterval, SyncPolicyLevel:
+	default:
+		errs = append(errs, fmt.Errorf("unrecognized sync policy: %q", o.SyncPolicy))
+	}
+
+	if o.SyncLevel != "" {
+		if err := level.UnmarshalText([]byte(o.SyncLevel)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if o.LowSpaceLevel != "" {
+		if err := level.UnmarshalText([]byte(o.LowSpaceLevel)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if o.StacktraceLevel != "" {
+		if err := level.UnmarshalText([]byte(o.StacktraceLevel)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	switch o.Mode {
+	case "", ModeDevelopment, ModeProduction:
+	default:
+		errs = append(errs, fmt.Errorf("unrecognized mode: %q", o.Mode))
+	}
+
+	if intValue(o.SamplingInitial) < 0 || intValue(o.SamplingThereafter) < 0 || o.SamplingTick < 0 {
+		errs = append(errs, errors.New("negative sampling options"))
+	}
+
+	if o.Verbosity < 0 {
+		errs = append(errs, fmt.Errorf("negative verbosity: %d", o.Verbosity))
+	}
+
+	switch o.StacktraceMode {
+	case "", StacktraceFull, StacktraceTrimmed:
+	default:
+		errs = append(errs, fmt.Errorf("unrecognized stacktrace mode: %q", o.StacktraceMode))
+	}
+
+	if o.SharedFile && !sharedFileSupported {
+		errs = append(errs, errors.New("'SharedFile' is not supported on this platform"))
+	}
+
 	if o.DisableConsole && o.DisableFile {
 		errs = append(errs, errors.New("no enabled logger, one or more of "+
 			"(DisableConsole, DisableFile) must be set to false"))
@@ -151,6 +670,116 @@ func (o *Options) Validate() []error {
 	return errs
 }
 
+// applyMode sets the options which are not set to the preset of the Mode.
+func (o *Options) applyMode() {
+	var preset *Options
+	switch o.Mode {
+	case ModeDevelopment:
+		preset = NewDevelopmentOptions()
+	case ModeProduction:
+		preset = NewProductionOptions()
+		if o.Output != "" {
+			o.DisableFile = false
+			o.DisableRotate = false
+		}
+	default:
+		return
+	}
+	o.Development = o.Development || preset.Development
+	setStringDefault(&o.ConsoleFormat, preset.ConsoleFormat)
+	setStringDefault(&o.ConsoleLevel, preset.ConsoleLevel)
+	setStringDefault(&o.FileLevel, preset.FileLevel)
+	setStringDefault(&o.StacktraceLevel, preset.StacktraceLevel)
+	if o.SamplingInitial == nil {
+		o.SamplingInitial = preset.SamplingInitial
+	}
+	if o.SamplingThereafter == nil {
+		o.SamplingThereafter = preset.SamplingThereafter
+	}
+	if o.SamplingTick == 0 {
+		o.SamplingTick = preset.SamplingTick
+	}
+}
+
+func setStringDefault(s *string, def string) {
+	if *s == "" {
+		*s = def
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+// intValue returns the value of the optional int, 0 if it is nil.
+func intValue(i *int) int {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
+
+func (o *Options) development() bool {
+	return o.Development || o.Mode == ModeDevelopment
+}
+
+func (o *Options) consoleFormat() string {
+	if o.ConsoleFormat == "" {
+		return FormatConsole
+	}
+	return o.ConsoleFormat
+}
+
+func (o *Options) fileFormat() string {
+	if o.FileFormat != "" {
+		return o.FileFormat
+	}
+	if o.DisableFileJson {
+		return FormatConsole
+	}
+	return FormatJSON
+}
+
+func (o *Options) lineEnding() string {
+	if o.LineEnding == "" {
+		return zapcore.DefaultLineEnding
+	}
+	if unquoted, err := strconv.Unquote(`"` + o.LineEnding + `"`); err == nil {
+		return unquoted
+	}
+	return o.LineEnding
+}
+
+// keyOrDefault returns the key, or the def if the key is empty.
+func keyOrDefault(key, def string) string {
+	if key == "" {
+		return def
+	}
+	return key
+}
+
+func isValidFormat(format string) bool {
+	switch format {
+	case FormatConsole, FormatJSON, FormatLogfmt, FormatECS, FormatGCP, FormatDev:
+		return true
+	}
+	return false
+}
+
+func (o *Options) fileMode() os.FileMode {
+	if o.FileMode == 0 {
+		return DefaultFileMode
+	}
+	return os.FileMode(o.FileMode).Perm()
+}
+
+func (o *Options) dirMode() os.FileMode {
+	if o.DirMode == 0 {
+		return DefaultDirMode
+	}
+	return os.FileMode(o.DirMode).Perm()
+}
+
 func (o *Options) String() string {
 	data, _ := json.Marshal(o)
 	return string(data)
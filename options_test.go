@@ -1,7 +1,10 @@
 package log
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -44,6 +47,21 @@ func Test_Default_Options_Validate(t *testing.T) {
 		assert.Equal(t, "unrecognized level: \"errorlevel\"", errs[1].Error())
 	})
 
+	t.Run("unrecognized encoder format error", func(t *testing.T) {
+		opts := NewOptions()
+		opts.TimeFormat = "rfc3339-nano"
+		opts.LevelFormat = "upper"
+		opts.CallerFormat = "full"
+		opts.DurationFormat = "ms"
+		errs := opts.Validate()
+
+		assert.Equal(t, 4, len(errs))
+		assert.Equal(t, "unrecognized time format: \"rfc3339-nano\"", errs[0].Error())
+		assert.Equal(t, "unrecognized level format: \"upper\"", errs[1].Error())
+		assert.Equal(t, "unrecognized caller format: \"full\"", errs[2].Error())
+		assert.Equal(t, "unrecognized duration format: \"ms\"", errs[3].Error())
+	})
+
 	t.Run("options string", func(t *testing.T) {
 		opts := NewOptions()
 		opts.ConsoleLevel = "errorlevel"
@@ -56,3 +74,32 @@ func Test_Default_Options_Validate(t *testing.T) {
 		assert.NotContains(t, output, "encoder")
 	})
 }
+
+func TestEncoderKeyOptions(t *testing.T) {
+	dir := t.TempDir()
+	opts := NewOptions()
+	opts.DisableConsole = true
+	opts.DisableFile = false
+	opts.DisableFileTime = false
+	opts.Output = dir
+	opts.FilenameEncoder = func() string {
+		return "test.log"
+	}
+	opts.MessageKey = "message"
+	opts.LevelKey = "severity"
+	opts.TimeKey = "ts"
+	opts.NameKey = "name"
+	opts.LineEnding = "\\r\\n"
+	opts.TimeFormat = "epoch-millis"
+	opts.LevelFormat = "lowercase"
+	opts.DurationFormat = "string"
+	assert.Equal(t, 0, len(opts.Validate()))
+
+	l := New(opts)
+	l.Infot("hello", Duration("elapsed", time.Second))
+	assert.NoError(t, l.Close())
+
+	content, err := os.ReadFile(filepath.Join(dir, "test.log"))
+	assert.NoError(t, err)
+	assert.Regexp(t, `^\{"severity":"info","ts":\d+(\.\d+)?,"message":"hello","elapsed":"1s"\}\r\n$`, string(content))
+}
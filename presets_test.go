@@ -0,0 +1,137 @@
+package log
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDevelopmentOptions(t *testing.T) {
+	opts := NewDevelopmentOptions()
+	assert.Equal(t, 0, len(opts.Validate()))
+	assert.Equal(t, FormatDev, opts.consoleFormat())
+	assert.True(t, opts.development())
+
+	// the mode implies the development
+	opts.Development = false
+	opts.DisableConsole = true
+	opts.DisableFile = false
+	opts.Output = t.TempDir()
+	l := New(opts)
+	assert.True(t, l.Enabled(DebugLevel))
+	assert.Panics(t, func() { l.DPanic("dpanic") })
+	// the invalid key-value pairs are reported once by zap
+	assert.NotPanics(t, func() { l.Info("odd", "key") })
+	assert.NotPanics(t, func() { l.Info("valid", String("field", "v"), "key", 1) })
+	assert.NoError(t, l.Close())
+
+	content, err := os.ReadFile(l.EncodedFilename())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(content), "Ignored key without a value."))
+}
+
+func TestProductionOptions(t *testing.T) {
+	opts := NewProductionOptions()
+	assert.Equal(t, 0, len(opts.Validate()))
+	assert.Equal(t, "", opts.Output)
+	assert.True(t, opts.DisableFile)
+	assert.Equal(t, FormatJSON, opts.fileFormat())
+	assert.False(t, opts.development())
+
+	// the production mode enables the rotated log files in the Output
+	opts.DisableConsole = true
+	opts.Output = t.TempDir()
+	opts.SamplingTick = time.Minute
+	l := New(opts)
+	assert.False(t, l.Enabled(DebugLevel))
+	assert.NotPanics(t, func() { l.DPanic("dpanic") })
+	for i := 0; i < 300; i++ {
+		l.Infot("repeated")
+	}
+	l.Errort("failed")
+	assert.NoError(t, l.Close())
+
+	content, err := os.ReadFile(l.EncodedFilename())
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	// 1 dpanic, 100 initial and 2 thereafter repeated, 1 error
+	assert.Equal(t, 104, len(lines))
+	assert.Contains(t, lines[0], `"caller":"log/presets_test.go:`)
+	assert.NotContains(t, lines[1], `"stack":`)
+	assert.Contains(t, lines[len(lines)-1], `"msg":"failed","stack":"github.com/shipengqi/log.TestProductionOptions`)
+}
+
+func TestModeOptionsValidate(t *testing.T) {
+	opts := NewOptions()
+	opts.Mode = "staging"
+	opts.SamplingInitial = intPtr(-1)
+	errs := opts.Validate()
+	assert.Equal(t, 2, len(errs))
+	assert.Equal(t, `unrecognized mode: "staging"`, errs[0].Error())
+	assert.Equal(t, "negative sampling options", errs[1].Error())
+}
+
+func TestModePresets(t *testing.T) {
+	opts := &Options{
+		Mode:           ModeProduction,
+		DisableConsole: true,
+		Output:         t.TempDir(),
+		ConsoleLevel:   WarnLevel.String(),
+	}
+	l := New(opts)
+	assert.False(t, opts.DisableFile)
+	assert.False(t, opts.DisableRotate)
+	assert.Equal(t, FormatJSON, opts.ConsoleFormat)
+	assert.Equal(t, WarnLevel.String(), opts.ConsoleLevel)
+	assert.Equal(t, InfoLevel.String(), opts.FileLevel)
+	assert.Equal(t, ErrorLevel.String(), opts.StacktraceLevel)
+	assert.Equal(t, 100, *opts.SamplingInitial)
+	assert.Equal(t, 100, *opts.SamplingThereafter)
+	assert.Equal(t, time.Second, opts.SamplingTick)
+	// the zero retention options keep all the backups
+	assert.Equal(t, 0, opts.MaxBackups)
+	assert.Equal(t, 0, opts.MaxAge)
+	assert.False(t, opts.Compress)
+	assert.NoError(t, l.Close())
+
+	opts = &Options{Mode: ModeDevelopment, DisableConsole: true, DisableFile: true}
+	l = New(opts)
+	assert.True(t, opts.Development)
+	assert.Equal(t, FormatDev, opts.ConsoleFormat)
+	assert.Equal(t, DebugLevel.String(), opts.FileLevel)
+	assert.Panics(t, func() { l.DPanic("dpanic") })
+	assert.NoError(t, l.Close())
+}
+
+func TestModeExplicitZero(t *testing.T) {
+	opts := &Options{
+		Mode:               ModeProduction,
+		DisableFile:        true,
+		SamplingInitial:    intPtr(0),
+		SamplingThereafter: intPtr(0),
+	}
+	opts.applyMode()
+	assert.Equal(t, 0, *opts.SamplingInitial)
+	assert.Equal(t, 0, *opts.SamplingThereafter)
+	assert.Equal(t, 0, opts.MaxBackups)
+	assert.Equal(t, 0, opts.MaxAge)
+	// without the Output, the production mode logs to the console only
+	assert.True(t, opts.DisableFile)
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	opts = NewProductionOptions()
+	opts.AddFlags(fs)
+	assert.NoError(t, fs.Parse([]string{"--log.sampling-initial=0"}))
+	opts.applyMode()
+	assert.Equal(t, 0, *opts.SamplingInitial)
+
+	l := New(opts)
+	for i := 0; i < 300; i++ {
+		assert.NotNil(t, l.Check(InfoLevel, "repeated"))
+	}
+	assert.NoError(t, l.Close())
+}
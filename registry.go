@@ -0,0 +1,175 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+)
+
+// writers is the process-wide registry of the log file writers, Loggers
+// writing to the same logfile series share one writer, so that they don't
+// rotate the same file independently.
+var writers = &writerRegistry{
+	entries: map[string]*registryEntry{},
+}
+
+type registryEntry struct {
+	writer *rotateWriter
+	refs   int
+}
+
+type writerRegistry struct {
+	mu      sync.Mutex
+	entries map[string]*registryEntry
+}
+
+// acquire returns the registered writer of the logfile series of the path,
+// or creates a new one. The registered writer is only shared with the
+// Loggers of the compatible options, see writerOptionsEqual, it's never
+// reconfigured, errWriterInUse is returned otherwise. The returned
+// writerRef must be closed when it is no longer used.
+func (r *writerRegistry) acquire(path string, opts *Options, encoder FilenameEncoder, header func() []byte) (*writerRef, error) {
+	key := writerKey(path)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.entries[key]
+	if ok {
+		if !writerOptionsEqual(e.writer.opts, opts) {
+			return nil, fmt.Errorf("%w: %s", errWriterInUse, path)
+		}
+	} else {
+		e = &registryEntry{writer: newRotateWriter(opts, encoder, header)}
+	}
+	if opts.DisableRotate {
+		if err := e.writer.open(path); err != nil {
+			if !ok {
+				_ = e.writer.Close()
+			}
+			return nil, err
+		}
+	}
+	if !ok {
+		r.entries[key] = e
+	}
+	e.refs++
+	return &writerRef{rotateWriter: e.writer, key: key, registry: r}, nil
+}
+
+// release decreases the reference count of the writer, and closes it when
+// the last reference is released.
+func (r *writerRegistry) release(key string) error {
+	r.mu.Lock()
+	e, ok := r.entries[key]
+	if !ok {
+		r.mu.Unlock()
+		return nil
+	}
+	e.refs--
+	if e.refs > 0 {
+		r.mu.Unlock()
+		return nil
+	}
+	delete(r.entries, key)
+	r.mu.Unlock()
+
+	return e.writer.Close()
+}
+
+// refs returns the reference count of the writer of the path.
+func (r *writerRegistry) refs(path string) int {
+	key := writerKey(path)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e, ok := r.entries[key]; ok {
+		return e.refs
+	}
+	return 0
+}
+
+// writerKey returns the key of the logfile series of the path, which is the
+// absolute output directory and the name of the series, e.g.
+// /var/log/app-20060102.log for /var/log/app-20060103.log. The Loggers of a
+// series share the writer after the time-based rotations.
+func writerKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	stem, layout, ext := logFileSeries(path)
+	if layout != "" {
+		stem += "-" + layout
+	}
+	return filepath.Join(filepath.Dir(path), stem+ext)
+}
+
+// errWriterInUse is returned by acquire when the logfile series is written
+// by another Logger with the different options.
+var errWriterInUse = errors.New("log file is used by another logger with different options")
+
+// writerOptionsEqual reports whether the Loggers of the options can share a
+// writer, the options of the log files, the rotation and the hooks are equal.
+func writerOptionsEqual(a, b *Options) bool {
+	return a.DisableRotate == b.DisableRotate &&
+		a.SharedFile == b.SharedFile &&
+		a.MaxSize == b.MaxSize &&
+		a.MaxBackups == b.MaxBackups &&
+		a.MaxAge == b.MaxAge &&
+		a.Compress == b.Compress &&
+		a.SyncPolicy == b.SyncPolicy &&
+		a.SyncInterval == b.SyncInterval &&
+		a.BufferSize == b.BufferSize &&
+		a.FlushInterval == b.FlushInterval &&
+		a.FileMode == b.FileMode &&
+		a.DirMode == b.DirMode &&
+		intPtrEqual(a.FileUID, b.FileUID) &&
+		intPtrEqual(a.FileGID, b.FileGID) &&
+		a.FileHeader == b.FileHeader &&
+		a.TimeZone == b.TimeZone &&
+		clockEqual(a.Clock, b.Clock) &&
+		funcEqual(a.OnRotate, b.OnRotate) &&
+		funcEqual(a.OnRemove, b.OnRemove)
+}
+
+func intPtrEqual(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func clockEqual(a, b Clock) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	t := reflect.TypeOf(a)
+	return t == reflect.TypeOf(b) && t.Comparable() && a == b
+}
+
+// funcEqual reports whether the funcs are both nil or the same function,
+// the closures of a function literal are equal.
+func funcEqual(a, b interface{}) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+// writerRef is a reference to a registered writer, closing it releases the
+// reference.
+type writerRef struct {
+	*rotateWriter
+
+	key      string
+	registry *writerRegistry
+	once     sync.Once
+}
+
+func (w *writerRef) Close() error {
+	var err error
+	w.once.Do(func() {
+		err = w.registry.release(w.key)
+	})
+	return err
+}
@@ -0,0 +1,148 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriterRegistry(t *testing.T) {
+	dir := t.TempDir()
+	newOpts := func() *Options {
+		opts := NewOptions()
+		opts.DisableConsole = true
+		opts.DisableFile = false
+		opts.Output = dir
+		opts.FilenameEncoder = func() string {
+			return "test.log"
+		}
+		return opts
+	}
+	filename := filepath.Join(dir, "test.log")
+
+	t.Run("share writer", func(t *testing.T) {
+		l1 := New(newOpts())
+		l2 := New(newOpts())
+		assert.Equal(t, 2, writers.refs(filename))
+
+		l1.Info("first")
+		assert.NoError(t, l1.Close())
+		assert.NoError(t, l1.Close())
+		assert.Equal(t, 1, writers.refs(filename))
+
+		l2.Info("second")
+		assert.NoError(t, l2.Close())
+		assert.Equal(t, 0, writers.refs(filename))
+
+		content, err := os.ReadFile(filename)
+		assert.NoError(t, err)
+		assert.Contains(t, string(content), "first")
+		assert.Contains(t, string(content), "second")
+	})
+
+	t.Run("configure closes the previous logger", func(t *testing.T) {
+		Configure(newOpts())
+		Configure(newOpts())
+		assert.Equal(t, 1, writers.refs(filename))
+
+		Configure(NewOptions())
+		assert.Equal(t, 0, writers.refs(filename))
+	})
+
+	t.Run("share writer of the series", func(t *testing.T) {
+		opts := newOpts()
+		opts.FilenameEncoder = func() string {
+			return "app-20060102.log"
+		}
+		l1 := New(opts)
+		opts = newOpts()
+		opts.FilenameEncoder = func() string {
+			return "app-20060103.log"
+		}
+		l2 := New(opts)
+		other := newOpts()
+		other.FilenameEncoder = func() string {
+			return "app2-20060103.log"
+		}
+		l3 := New(other)
+		assert.Equal(t, 2, writers.refs(filepath.Join(dir, "app-20060104.log")))
+		assert.Equal(t, 1, writers.refs(filepath.Join(dir, "app2-20060104.log")))
+		assert.NoError(t, l1.Close())
+		assert.NoError(t, l2.Close())
+		assert.NoError(t, l3.Close())
+		assert.Equal(t, 0, writers.refs(filepath.Join(dir, "app-20060104.log")))
+	})
+
+	t.Run("configure replaces the writer of the other options", func(t *testing.T) {
+		name := "reload-20060102.log"
+		opts := newOpts()
+		opts.DisableRotate = false
+		opts.FilenameEncoder = func() string {
+			return name
+		}
+		Configure(opts)
+		Info("first")
+
+		var rotated []string
+		opts = newOpts()
+		opts.DisableRotate = false
+		opts.FileMode = 0o600
+		opts.FilenameEncoder = func() string {
+			return name
+		}
+		opts.OnRotate = func(oldname, newname string) {
+			rotated = append(rotated, filepath.Base(oldname))
+		}
+		Configure(opts)
+		Info("reloaded")
+		name = "reload-20060103.log"
+		Info("second")
+		Configure(NewOptions())
+
+		assert.Equal(t, []string{"reload-20060102.log"}, rotated)
+		info, err := os.Stat(filepath.Join(dir, name))
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	})
+
+	t.Run("configure keeps the writer of the children", func(t *testing.T) {
+		Configure(newOpts())
+		child := L().Named("child")
+		Configure(newOpts())
+		assert.Equal(t, 1, writers.refs(filename))
+		child.Info("child of the previous logger")
+		Configure(NewOptions())
+
+		content, err := os.ReadFile(filename)
+		assert.NoError(t, err)
+		assert.Contains(t, string(content), "child of the previous logger")
+	})
+
+	t.Run("released writer is not reopened", func(t *testing.T) {
+		opts := newOpts()
+		opts.FilenameEncoder = func() string {
+			return "released.log"
+		}
+		l := New(opts)
+		child := l.Named("child")
+		child.Info("open")
+		assert.NoError(t, l.Close())
+		assert.NoError(t, os.Remove(filepath.Join(dir, "released.log")))
+
+		child.Info("closed")
+		assert.NoFileExists(t, filepath.Join(dir, "released.log"))
+		assert.Equal(t, 0, writers.refs(filepath.Join(dir, "released.log")))
+	})
+
+	t.Run("incompatible options", func(t *testing.T) {
+		l := New(newOpts())
+		defer func() { _ = l.Close() }()
+
+		opts := newOpts()
+		opts.MaxBackups = 3
+		assert.PanicsWithError(t, errWriterInUse.Error()+": "+filename, func() { New(opts) })
+		assert.Equal(t, 1, writers.refs(filename))
+	})
+}
@@ -0,0 +1,148 @@
+package log
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// The keys of the resource fields.
+const (
+	ServiceKey      = "service"
+	VersionKey      = "version"
+	EnvironmentKey  = "environment"
+	HostnameKey     = "hostname"
+	PIDKey          = "pid"
+	ContainerIDKey  = "container_id"
+	PodNameKey      = "pod_name"
+	PodNamespaceKey = "pod_namespace"
+	NodeNameKey     = "node_name"
+)
+
+var (
+	// _cgroupPath and _mountinfoPath are the files the container id is
+	// detected from, they are variables for the tests.
+	_cgroupPath    = "/proc/self/cgroup"
+	_mountinfoPath = "/proc/self/mountinfo"
+
+	// the env vars which are commonly set by the Kubernetes downward API
+	_podNameEnvs      = []string{"POD_NAME", "MY_POD_NAME", "K8S_POD_NAME", "KUBERNETES_POD_NAME"}
+	_podNamespaceEnvs = []string{"POD_NAMESPACE", "MY_POD_NAMESPACE", "K8S_POD_NAMESPACE", "KUBERNETES_NAMESPACE"}
+	_nodeNameEnvs     = []string{"NODE_NAME", "MY_NODE_NAME", "K8S_NODE_NAME", "KUBERNETES_NODE_NAME"}
+
+	_containerIDRegexp = regexp.MustCompile(`[0-9a-f]{64}`)
+)
+
+// resourceFields returns the static fields describing the service and the
+// process, which are added to every entry of the logger. The fields are
+// placed under the ResourceNamespace if it is not empty.
+func resourceFields(opts *Options) []Field {
+	var fields []Field
+	add := func(key, val string) {
+		if val != "" {
+			fields = append(fields, String(key, val))
+		}
+	}
+	add(ServiceKey, opts.Service)
+	add(VersionKey, opts.Version)
+	add(EnvironmentKey, opts.Environment)
+
+	if opts.DetectResource {
+		if !opts.DisableHostname {
+			add(HostnameKey, detectHostname())
+		}
+		if !opts.DisablePID {
+			fields = append(fields, Int(PIDKey, os.Getpid()))
+		}
+		if !opts.DisableContainerID {
+			add(ContainerIDKey, detectContainerID())
+		}
+		if !opts.DisablePodMetadata {
+			add(PodNameKey, lookupEnv(_podNameEnvs))
+			add(PodNamespaceKey, lookupEnv(_podNamespaceEnvs))
+			add(NodeNameKey, lookupEnv(_nodeNameEnvs))
+		}
+	}
+
+	if len(fields) == 0 || opts.ResourceNamespace == "" {
+		return fields
+	}
+	return []Field{Object(opts.ResourceNamespace, resourceObject(fields))}
+}
+
+type resourceObject []Field
+
+func (fields resourceObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	return nil
+}
+
+// detectHostname returns the HOSTNAME env var, which is the pod name in
+// Kubernetes, or the hostname reported by the kernel.
+func detectHostname() string {
+	if hostname := os.Getenv("HOSTNAME"); hostname != "" {
+		return hostname
+	}
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// detectContainerID returns the id of the container the process runs in, it
+// is detected from the cgroup paths, e.g.
+//
+//	0::/kubepods/burstable/pod<uid>/cri-containerd-<id>.scope
+//
+// or the mounts of the container runtime with cgroup v2, e.g.
+//
+//	/var/lib/docker/containers/<id>/hostname /etc/hostname
+func detectContainerID() string {
+	if id := scanFile(_cgroupPath, func(line string) string {
+		// the id is in the last path element
+		return _containerIDRegexp.FindString(line[strings.LastIndexByte(line, '/')+1:])
+	}); id != "" {
+		return id
+	}
+	return scanFile(_mountinfoPath, func(line string) string {
+		i := strings.Index(line, "/containers/")
+		if i < 0 {
+			return ""
+		}
+		rest := line[i+len("/containers/"):]
+		if loc := _containerIDRegexp.FindStringIndex(rest); loc != nil && loc[0] == 0 {
+			return rest[:loc[1]]
+		}
+		return ""
+	})
+}
+
+// scanFile returns the first non-empty result of the match of the lines of
+// the file.
+func scanFile(name string, match func(line string) string) string {
+	f, err := os.Open(name)
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = f.Close() }()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if v := match(scanner.Text()); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// lookupEnv returns the value of the first env var set.
+func lookupEnv(keys []string) string {
+	for _, key := range keys {
+		if v := os.Getenv(key); v != "" {
+			return v
+		}
+	}
+	return ""
+}
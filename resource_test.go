@@ -0,0 +1,110 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testContainerID = "3f4b6a1c2d5e7f8091a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708"
+
+func setResourcePaths(t *testing.T, cgroup, mountinfo string) {
+	dir := t.TempDir()
+	oldCgroup, oldMountinfo := _cgroupPath, _mountinfoPath
+	_cgroupPath = filepath.Join(dir, "cgroup")
+	_mountinfoPath = filepath.Join(dir, "mountinfo")
+	t.Cleanup(func() {
+		_cgroupPath, _mountinfoPath = oldCgroup, oldMountinfo
+	})
+	assert.NoError(t, os.WriteFile(_cgroupPath, []byte(cgroup), 0o644))
+	assert.NoError(t, os.WriteFile(_mountinfoPath, []byte(mountinfo), 0o644))
+}
+
+func TestDetectContainerID(t *testing.T) {
+	tests := []struct {
+		name      string
+		cgroup    string
+		mountinfo string
+		expected  string
+	}{
+		{"docker", "12:memory:/docker/" + testContainerID + "\n", "", testContainerID},
+		{"containerd", "0::/kubepods.slice/kubepods-burstable.slice/cri-containerd-" + testContainerID + ".scope\n", "", testContainerID},
+		{"cgroup v2", "0::/\n", "1 2 0:3 /var/lib/docker/containers/" + testContainerID + "/hostname /etc/hostname rw\n", testContainerID},
+		{"host", "0::/user.slice/user-1000.slice/session-1.scope\n", "22 1 8:1 / / rw,relatime - ext4 /dev/sda1 rw\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setResourcePaths(t, tt.cgroup, tt.mountinfo)
+			assert.Equal(t, tt.expected, detectContainerID())
+		})
+	}
+}
+
+func TestResourceFields(t *testing.T) {
+	setResourcePaths(t, "0::/docker/"+testContainerID+"\n", "")
+	t.Setenv("HOSTNAME", "app-7d9f")
+	t.Setenv("POD_NAME", "app-7d9f")
+	t.Setenv("POD_NAMESPACE", "default")
+	t.Setenv("NODE_NAME", "node-1")
+
+	t.Run("static", func(t *testing.T) {
+		opts := NewOptions()
+		opts.Service = "api"
+		opts.Version = "1.2.3"
+		opts.Environment = "production"
+		assert.Equal(t, []Field{
+			String(ServiceKey, "api"),
+			String(VersionKey, "1.2.3"),
+			String(EnvironmentKey, "production"),
+		}, resourceFields(opts))
+		assert.Nil(t, resourceFields(NewOptions()))
+	})
+
+	t.Run("detect", func(t *testing.T) {
+		opts := NewOptions()
+		opts.Service = "api"
+		opts.DetectResource = true
+		assert.Equal(t, []Field{
+			String(ServiceKey, "api"),
+			String(HostnameKey, "app-7d9f"),
+			Int(PIDKey, os.Getpid()),
+			String(ContainerIDKey, testContainerID),
+			String(PodNameKey, "app-7d9f"),
+			String(PodNamespaceKey, "default"),
+			String(NodeNameKey, "node-1"),
+		}, resourceFields(opts))
+
+		opts.DisableHostname = true
+		opts.DisablePID = true
+		opts.DisableContainerID = true
+		opts.DisablePodMetadata = true
+		assert.Equal(t, []Field{String(ServiceKey, "api")}, resourceFields(opts))
+	})
+
+	t.Run("logger", func(t *testing.T) {
+		dir := t.TempDir()
+		opts := NewOptions()
+		opts.DisableConsole = true
+		opts.DisableFile = false
+		opts.DisableFileTime = true
+		opts.Output = dir
+		opts.Service = "api"
+		opts.DetectResource = true
+		opts.DisableContainerID = true
+		opts.DisablePodMetadata = true
+		opts.ResourceNamespace = "resource"
+
+		l := New(opts)
+		l.WithValues(String("user", "alice")).Infot("hello")
+		assert.NoError(t, l.Close())
+
+		content, err := os.ReadFile(l.EncodedFilename())
+		assert.NoError(t, err)
+		expected := fmt.Sprintf(`{"level":"INFO","msg":"hello","resource":{"service":"api","hostname":"app-7d9f","pid":%d},"user":"alice"}`, os.Getpid())
+		assert.Equal(t, expected, strings.TrimSpace(string(content)))
+	})
+}
@@ -0,0 +1,625 @@
+package log
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMaxSize is the max size in MB of the logfile when MaxSize is not set.
+	defaultMaxSize = 100
+	// backupTimeFormat is the timestamp layout of the size-based backups,
+	// e.g. <name>-2006-01-02T15-04-05.000.log
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+	// compressSuffix is the suffix of the compressed backups.
+	compressSuffix = ".gz"
+)
+
+// errWriterClosed is returned by the writes after the writer is closed.
+var errWriterClosed = errors.New("log file writer is closed")
+
+// rotateEvent is a rotation of the logfile, with the options and the clock
+// of the writer at the time of the rotation.
+type rotateEvent struct {
+	oldname string
+	newname string
+	opts    *Options
+	clock   Clock
+}
+
+// rotateWriter is a zapcore.WriteSyncer that writes to the file returned by
+// the FilenameEncoder. Unless DisableRotate is set, it rotates the file when
+// the encoded filename changes (time-based rotation) or when the file grows
+// beyond MaxSize (size-based rotation), and removes old files according to
+// MaxBackups and MaxAge.
+type rotateWriter struct {
+	mu       sync.Mutex
+	opts     *Options
+	clock    Clock
+	encoder  FilenameEncoder
+	header   func() []byte
+	filename string
+	file     *os.File
+	buf      *bufio.Writer
+	size     int64
+
+	// lastCheck is the last time the shared logfile was checked
+	lastCheck time.Time
+
+	// closing is set when Close starts, the writes of the rotation hooks
+	// still work until it waits for them, closed is set after that and
+	// fails the writes, the writer is never reopened
+	closing bool
+	closed  bool
+
+	// millPending is the rotation events waiting for millRun, millCh
+	// signals millRun that there are pending events
+	millPending []rotateEvent
+	millCh      chan struct{}
+	millDone    chan struct{}
+
+	flushStop chan struct{}
+	flushDone chan struct{}
+}
+
+func newRotateWriter(opts *Options, encoder FilenameEncoder, header func() []byte) *rotateWriter {
+	return &rotateWriter{
+		opts:    opts,
+		clock:   opts.clock(),
+		encoder: encoder,
+		header:  header,
+	}
+}
+
+// open opens the logfile eagerly, so that the errors can be reported on startup.
+func (w *rotateWriter) open(filename string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file != nil && w.filename == filename {
+		return nil
+	}
+	return w.reopen(filename, 0)
+}
+
+func (w *rotateWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, errWriterClosed
+	}
+	// Get the current filename from encoder
+	filename := w.filename
+	if !w.opts.DisableRotate || filename == "" {
+		filename = filepath.Join(w.opts.Output, w.encoder())
+	}
+	switch {
+	case w.file == nil:
+		err = w.reopen(filename, len(p))
+	case filename != w.filename:
+		// Filename changed, indicates time period changed
+		err = w.rotate(filename)
+	case w.opts.SharedFile:
+		// the logfile may be rotated by another process
+		if w.clock.Now().Sub(w.lastCheck) >= sharedCheckInterval {
+			err = w.checkShared(len(p))
+		}
+	case !w.opts.DisableRotate && w.size > 0 && w.size+int64(len(p)) > w.max():
+		err = w.rotate("")
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if w.buf != nil {
+		n, err = w.buf.Write(p)
+	} else {
+		n, err = w.file.Write(p)
+	}
+	w.size += int64(n)
+	if err == nil && w.opts.SyncPolicy == SyncPolicyWrite {
+		err = w.sync()
+	}
+	return n, err
+}
+
+// Sync flushes the buffered data and commits the current logfile to disk.
+func (w *rotateWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.sync()
+}
+
+// Close waits for the pending rotation hooks and retention to finish, and
+// closes the current logfile. The writes fail after Close.
+func (w *rotateWriter) Close() error {
+	w.mu.Lock()
+	w.closing = true
+	done := w.millDone
+	if w.millCh != nil {
+		close(w.millCh)
+		w.millCh = nil
+		w.millDone = nil
+	}
+	w.mu.Unlock()
+
+	// wait without holding the lock, the hooks may write logs
+	if done != nil {
+		<-done
+	}
+
+	w.mu.Lock()
+	w.closed = true
+	err := w.close()
+	flushDone := w.flushDone
+	if w.flushStop != nil {
+		close(w.flushStop)
+		w.flushStop = nil
+		w.flushDone = nil
+	}
+	w.mu.Unlock()
+
+	if flushDone != nil {
+		<-flushDone
+	}
+	return err
+}
+
+func (w *rotateWriter) flush() error {
+	if w.buf == nil {
+		return nil
+	}
+	return w.buf.Flush()
+}
+
+func (w *rotateWriter) sync() error {
+	if w.file == nil {
+		return nil
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+func (w *rotateWriter) close() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.flush()
+	if cerr := w.file.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	w.file = nil
+	w.buf = nil
+	return err
+}
+
+// setFile sets the current logfile, writes the header if required, and
+// starts the background flush if required by the options.
+func (w *rotateWriter) setFile(f *os.File, filename string, size int64, header bool) {
+	w.file = f
+	w.filename = filename
+	w.size = size
+	if w.opts.BufferSize > 0 {
+		w.buf = bufio.NewWriterSize(f, w.opts.BufferSize)
+	}
+	if header && w.header != nil {
+		var n int
+		if w.buf != nil {
+			n, _ = w.buf.Write(w.header())
+		} else {
+			n, _ = w.file.Write(w.header())
+		}
+		w.size += int64(n)
+	}
+	if w.flushStop == nil && (w.buf != nil || w.opts.SyncPolicy == SyncPolicyInterval) {
+		w.flushStop = make(chan struct{})
+		w.flushDone = make(chan struct{})
+		go w.flushRun(w.opts, w.clock, w.flushStop, w.flushDone)
+	}
+}
+
+// flushRun flushes the buffered data every FlushInterval, and commits the
+// current logfile to disk every SyncInterval if the SyncPolicy is interval.
+func (w *rotateWriter) flushRun(opts *Options, clock Clock, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	var flushC, syncC <-chan time.Time
+	if opts.BufferSize > 0 {
+		ticker := clock.NewTicker(durationOrDefault(opts.FlushInterval, DefaultFlushInterval))
+		defer ticker.Stop()
+		flushC = ticker.C
+	}
+	if opts.SyncPolicy == SyncPolicyInterval {
+		ticker := clock.NewTicker(durationOrDefault(opts.SyncInterval, DefaultSyncInterval))
+		defer ticker.Stop()
+		syncC = ticker.C
+	}
+	for {
+		var err error
+		select {
+		case <-flushC:
+			w.mu.Lock()
+			err = w.flush()
+			w.mu.Unlock()
+		case <-syncC:
+			w.mu.Lock()
+			err = w.sync()
+			w.mu.Unlock()
+		case <-stop:
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to flush log file: %v\n", err)
+		}
+	}
+}
+
+func (w *rotateWriter) max() int64 {
+	if w.opts.MaxSize <= 0 {
+		return int64(defaultMaxSize) * 1024 * 1024
+	}
+	return int64(w.opts.MaxSize) * 1024 * 1024
+}
+
+// rotate closes the current logfile and opens the next one. If next is
+// empty, the current logfile is renamed to a backup and a new file with the
+// same name is opened.
+func (w *rotateWriter) rotate(next string) error {
+	if w.opts.SharedFile {
+		return w.rotateShared(next)
+	}
+	if err := w.close(); err != nil {
+		return err
+	}
+	old := w.filename
+	if next == "" {
+		next = old
+		backup := backupName(old, w.backupTime())
+		if err := os.Rename(old, backup); err != nil {
+			return fmt.Errorf("can't rename log file: %s", err)
+		}
+		old = backup
+	}
+	if err := w.openNew(next); err != nil {
+		return err
+	}
+	w.mill(old, next)
+	return nil
+}
+
+func (w *rotateWriter) openNew(filename string) error {
+	f, err := openFile(w.opts, filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY)
+	if err != nil {
+		return fmt.Errorf("can't open new log file: %s", err)
+	}
+	var size int64
+	if info, err := f.Stat(); err == nil {
+		size = info.Size()
+	}
+	w.setFile(f, filename, size, size == 0)
+	return nil
+}
+
+// reopen opens the logfile after it was closed, and runs the rotation hooks
+// if the filename was changed in the meantime.
+func (w *rotateWriter) reopen(filename string, writeLen int) error {
+	prev := w.filename
+	if w.opts.SharedFile {
+		created, err := w.openShared(filename)
+		if err == nil && created && prev != filename {
+			w.mill(prev, filename)
+		}
+		return err
+	}
+	if err := w.openExistingOrNew(filename, writeLen); err != nil {
+		return err
+	}
+	if prev != filename {
+		w.mill(prev, filename)
+	}
+	return nil
+}
+
+func (w *rotateWriter) openExistingOrNew(filename string, writeLen int) error {
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return w.openNew(filename)
+	}
+	if err != nil {
+		return fmt.Errorf("error getting log file info: %s", err)
+	}
+	w.filename = filename
+	if !w.opts.DisableRotate && info.Size() > 0 && info.Size()+int64(writeLen) > w.max() {
+		return w.rotate("")
+	}
+
+	f, err := openFile(w.opts, filename, os.O_APPEND|os.O_WRONLY)
+	if err != nil {
+		// if we fail to open the old log file for some reason, just ignore
+		// it and open a new log file.
+		return w.openNew(filename)
+	}
+	w.setFile(f, filename, info.Size(), info.Size() == 0)
+	return nil
+}
+
+// mill runs the rotation hooks and the retention in a background goroutine,
+// so that slow hooks don't block logging. It never blocks, the events are
+// queued and the signal to millRun is coalesced, so that the hooks can log
+// through this writer.
+func (w *rotateWriter) mill(oldname, newname string) {
+	if w.opts.DisableRotate {
+		return
+	}
+	if w.opts.OnRotate == nil && !w.opts.Compress && w.opts.MaxBackups <= 0 && w.opts.MaxAge <= 0 {
+		return
+	}
+	w.millPending = append(w.millPending, rotateEvent{
+		oldname: oldname,
+		newname: newname,
+		opts:    w.opts,
+		clock:   w.clock,
+	})
+	if w.closing {
+		// the rotation of a hook, millRun is draining the pending events
+		return
+	}
+	if w.millCh == nil {
+		w.millCh = make(chan struct{}, 1)
+		w.millDone = make(chan struct{})
+		go w.millRun(w.millCh, w.millDone)
+	}
+	select {
+	case w.millCh <- struct{}{}:
+	default:
+	}
+}
+
+func (w *rotateWriter) millRun(ch <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	for range ch {
+		w.millPendingEvents(ch)
+	}
+	// the events queued before Close
+	w.millPendingEvents(ch)
+}
+
+// millPendingEvents runs the hooks of the pending events without holding
+// the lock, then the retention once, so that the retention never removes
+// the rotated logfiles of the events which are not milled yet.
+func (w *rotateWriter) millPendingEvents(closing <-chan struct{}) {
+	for {
+		w.mu.Lock()
+		events := w.millPending
+		w.millPending = nil
+		w.mu.Unlock()
+		if len(events) == 0 {
+			return
+		}
+		for _, ev := range events {
+			w.millEvent(ev, closing)
+		}
+		last := events[len(events)-1]
+		if err := removeExpired(last.opts, last.clock, last.newname, w.isPending); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to remove expired log files: %v\n", err)
+		}
+	}
+}
+
+// millEvent compresses the rotated logfile of the rotation event and runs
+// OnRotate, the closing channel is closed when the writer is closed.
+func (w *rotateWriter) millEvent(ev rotateEvent, closing <-chan struct{}) {
+	if ev.oldname == "" {
+		return
+	}
+	if _, err := os.Stat(ev.oldname); err != nil {
+		// removed by someone else, e.g. another process of the shared file
+		return
+	}
+	opts := ev.opts
+	compress := opts.Compress && !strings.HasSuffix(ev.oldname, compressSuffix)
+	if opts.SharedFile && (compress || opts.OnRotate != nil) {
+		// wait for the other processes to switch to the new logfile, the
+		// rotated logfile is left uncompressed if they don't switch
+		// before Close
+		unlock, ok := lockRotated(ev.clock, ev.oldname, closing)
+		if ok {
+			defer unlock()
+		}
+		compress = compress && ok
+	}
+	if compress {
+		compressed := ev.oldname + compressSuffix
+		if err := compressFile(opts, ev.oldname, compressed); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to compress log file: %v\n", err)
+		} else {
+			ev.oldname = compressed
+		}
+	}
+	if opts.OnRotate != nil {
+		opts.OnRotate(ev.oldname, ev.newname)
+	}
+}
+
+// isPending reports whether the rotated logfile has a rotation event which
+// is waiting for millRun.
+func (w *rotateWriter) isPending(name string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	name = strings.TrimSuffix(name, compressSuffix)
+	for _, ev := range w.millPending {
+		if ev.oldname == name {
+			return true
+		}
+	}
+	return false
+}
+
+// removeExpired removes the rotated siblings of the current logfile which
+// exceed MaxBackups or MaxAge, except the pending ones.
+func removeExpired(opts *Options, clock Clock, current string, pending func(string) bool) error {
+	if opts.MaxBackups <= 0 && opts.MaxAge <= 0 {
+		return nil
+	}
+	files, err := rotatedFiles(current)
+	if err != nil {
+		return err
+	}
+
+	var remove []string
+	if opts.MaxBackups > 0 && len(files) > opts.MaxBackups {
+		for _, f := range files[opts.MaxBackups:] {
+			remove = append(remove, f.name)
+		}
+		files = files[:opts.MaxBackups]
+	}
+	if opts.MaxAge > 0 {
+		cutoff := clock.Now().Add(-time.Duration(opts.MaxAge) * 24 * time.Hour)
+		for _, f := range files {
+			if f.modTime.Before(cutoff) {
+				remove = append(remove, f.name)
+			}
+		}
+	}
+
+	for _, name := range remove {
+		if pending(name) {
+			continue
+		}
+		if err = os.Remove(name); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to remove log file: %v\n", err)
+			continue
+		}
+		if opts.OnRemove != nil {
+			opts.OnRemove(name)
+		}
+	}
+	return nil
+}
+
+type logFileInfo struct {
+	name    string
+	modTime time.Time
+}
+
+// rotatedFiles returns the rotated siblings of the given logfile, sorted by
+// modification time, newest first.
+func rotatedFiles(current string) ([]logFileInfo, error) {
+	dir := filepath.Dir(current)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("can't read log file directory: %s", err)
+	}
+
+	var files []logFileInfo
+	base := filepath.Base(current)
+	for _, e := range entries {
+		if e.IsDir() || e.Name() == base || !isRotatedFile(e.Name(), base) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, logFileInfo{
+			name:    filepath.Join(dir, e.Name()),
+			modTime: info.ModTime(),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+	return files, nil
+}
+
+// filenameLayouts is the time layouts of the built-in filename encoders,
+// the longest first.
+var filenameLayouts = []string{minutelyFilenameLayout, hourlyFilenameLayout, dailyFilenameLayout}
+
+// logFileSeries returns the stem, the time layout and the extension of the
+// series of the logfile. For example, the series of app-20060102.log is
+// "app" with the daily layout, the layout is empty if the logfile is not
+// named by the time, e.g. test.log.
+func logFileSeries(filename string) (stem, layout, ext string) {
+	base := filepath.Base(filename)
+	ext = filepath.Ext(base)
+	stem = strings.TrimSuffix(base, ext)
+	for _, l := range filenameLayouts {
+		i := len(stem) - len(l)
+		if i < 2 || stem[i-1] != '-' {
+			continue
+		}
+		if _, err := time.Parse(l, stem[i:]); err == nil {
+			return stem[:i-1], l, ext
+		}
+	}
+	return stem, "", ext
+}
+
+// isRotatedFile reports whether name was produced by rotating a logfile of
+// the same series as current. The name must be the exact stem of the series
+// followed by "-" and the timestamp of the series, the timestamp of a
+// backup or both. For example, app-20060102.log and
+// app-20060102-2006-01-02T15-04-05.000.log(.gz) are both rotated siblings
+// of app-20060103.log, but app2-20060102.log is not.
+func isRotatedFile(name, current string) bool {
+	stem, layout, ext := logFileSeries(current)
+	name = strings.TrimSuffix(filepath.Base(name), compressSuffix)
+	if !strings.HasSuffix(name, ext) || !strings.HasPrefix(name, stem+"-") {
+		return false
+	}
+	timestamp := strings.TrimSuffix(name, ext)[len(stem)+1:]
+	if isTimestamp(backupTimeFormat, timestamp) {
+		return true
+	}
+	if layout == "" || len(timestamp) < len(layout) {
+		return false
+	}
+	if !isTimestamp(layout, timestamp[:len(layout)]) {
+		return false
+	}
+	rest := timestamp[len(layout):]
+	return rest == "" || (rest[0] == '-' && isTimestamp(backupTimeFormat, rest[1:]))
+}
+
+// isTimestamp reports whether s is a time of the layout.
+func isTimestamp(layout, s string) bool {
+	if len(s) != len(layout) {
+		return false
+	}
+	_, err := time.Parse(layout, s)
+	return err == nil
+}
+
+// backupTime returns the time of the backup filename, which is in UTC
+// unless the TimeZone is set.
+func (w *rotateWriter) backupTime() time.Time {
+	if w.opts.TimeZone == "" {
+		return w.clock.Now().UTC()
+	}
+	return w.clock.Now()
+}
+
+// backupName creates a new filename from the given name, inserting a
+// timestamp between the filename and the extension.
+func backupName(name string, t time.Time) string {
+	dir := filepath.Dir(name)
+	filename := filepath.Base(name)
+	ext := filepath.Ext(filename)
+	prefix := filename[:len(filename)-len(ext)]
+	timestamp := t.Format(backupTimeFormat)
+	return filepath.Join(dir, fmt.Sprintf("%s-%s%s", prefix, timestamp, ext))
+}
@@ -0,0 +1,313 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotateHooks(t *testing.T) {
+	t.Run("rotate when filename changed", func(t *testing.T) {
+		dir := t.TempDir()
+		name := "test-20060102.log"
+
+		var (
+			mu      sync.Mutex
+			rotated [][2]string
+		)
+		opts := NewOptions()
+		opts.DisableConsole = true
+		opts.DisableFile = false
+		opts.DisableRotate = false
+		opts.Output = dir
+		opts.FilenameEncoder = func() string {
+			return name
+		}
+		opts.OnRotate = func(oldname, newname string) {
+			mu.Lock()
+			defer mu.Unlock()
+			rotated = append(rotated, [2]string{oldname, newname})
+		}
+		l := New(opts)
+		l.Info("first")
+		name = "test-20060103.log"
+		l.Info("second")
+		assert.NoError(t, l.Close())
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, [][2]string{{
+			filepath.Join(dir, "test-20060102.log"),
+			filepath.Join(dir, "test-20060103.log"),
+		}}, rotated)
+	})
+
+	t.Run("remove expired backups", func(t *testing.T) {
+		dir := t.TempDir()
+		old := []string{
+			"test-20060101.log",
+			"test-20060102-2006-01-02T15-04-05.000.log",
+			"test-20060102.log",
+		}
+		for i, v := range old {
+			f := filepath.Join(dir, v)
+			assert.NoError(t, os.WriteFile(f, []byte("old\n"), 0o600))
+			mtime := time.Now().Add(time.Duration(i-len(old)) * time.Hour)
+			assert.NoError(t, os.Chtimes(f, mtime, mtime))
+		}
+		unrelated := filepath.Join(dir, "other.log")
+		assert.NoError(t, os.WriteFile(unrelated, []byte("other\n"), 0o600))
+
+		var (
+			mu      sync.Mutex
+			removed []string
+		)
+		opts := NewOptions()
+		opts.DisableConsole = true
+		opts.DisableFile = false
+		opts.DisableRotate = false
+		opts.MaxBackups = 1
+		opts.Output = dir
+		opts.FilenameEncoder = func() string {
+			return "test-20060103.log"
+		}
+		opts.OnRemove = func(name string) {
+			mu.Lock()
+			defer mu.Unlock()
+			removed = append(removed, name)
+		}
+		l := New(opts)
+		l.Info("new")
+		assert.NoError(t, l.Close())
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.ElementsMatch(t, []string{
+			filepath.Join(dir, old[0]),
+			filepath.Join(dir, old[1]),
+		}, removed)
+		assert.FileExists(t, filepath.Join(dir, old[2]))
+		assert.FileExists(t, unrelated)
+	})
+}
+
+func TestRetention(t *testing.T) {
+	newRetentionLogger := func(dir string, setup func(opts *Options)) *Logger {
+		opts := NewOptions()
+		opts.DisableConsole = true
+		opts.DisableFile = false
+		opts.DisableRotate = false
+		opts.Output = dir
+		opts.FilenameEncoder = func() string {
+			return "app-20060105.log"
+		}
+		setup(opts)
+		return New(opts)
+	}
+	writeFiles := func(t *testing.T, dir string, names ...string) {
+		for i, v := range names {
+			f := filepath.Join(dir, v)
+			assert.NoError(t, os.WriteFile(f, []byte("old\n"), 0o600))
+			mtime := time.Now().Add(time.Duration(i-len(names)) * time.Hour)
+			assert.NoError(t, os.Chtimes(f, mtime, mtime))
+		}
+	}
+
+	t.Run("keep the logs of the other series", func(t *testing.T) {
+		dir := t.TempDir()
+		foreign := []string{
+			"app2-20060101.log",
+			"app2-20060102-2006-01-02T15-04-05.000.log.gz",
+			"app-worker-20060101.log",
+			"app-20060101.txt",
+			".app.lock",
+		}
+		writeFiles(t, dir, foreign...)
+		writeFiles(t, dir, "app-20060101.log", "app-20060102-2006-01-02T15-04-05.000.log.gz", "app-20060103.log")
+
+		l := newRetentionLogger(dir, func(opts *Options) {
+			opts.MaxBackups = 1
+		})
+		l.Info("new")
+		assert.NoError(t, l.Close())
+
+		for _, v := range foreign {
+			assert.FileExists(t, filepath.Join(dir, v))
+		}
+		assert.NoFileExists(t, filepath.Join(dir, "app-20060101.log"))
+		assert.NoFileExists(t, filepath.Join(dir, "app-20060102-2006-01-02T15-04-05.000.log.gz"))
+		assert.FileExists(t, filepath.Join(dir, "app-20060103.log"))
+		assert.FileExists(t, filepath.Join(dir, "app-20060105.log"))
+	})
+
+	t.Run("remove the backups older than max age", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, "app-20060103.log", "app-20060104.log")
+		expired := filepath.Join(dir, "app-20060103.log")
+		mtime := time.Now().Add(-49 * time.Hour)
+		assert.NoError(t, os.Chtimes(expired, mtime, mtime))
+
+		l := newRetentionLogger(dir, func(opts *Options) {
+			opts.MaxAge = 2
+		})
+		l.Info("new")
+		assert.NoError(t, l.Close())
+
+		assert.NoFileExists(t, expired)
+		assert.FileExists(t, filepath.Join(dir, "app-20060104.log"))
+	})
+}
+
+func TestRotateHookLogs(t *testing.T) {
+	dir := t.TempDir()
+	var (
+		n int32
+		l *Logger
+	)
+	opts := NewOptions()
+	opts.DisableConsole = true
+	opts.DisableFile = false
+	opts.DisableRotate = false
+	opts.Output = dir
+	opts.FilenameEncoder = func() string {
+		return fmt.Sprintf("test-%d.log", atomic.LoadInt32(&n))
+	}
+	opts.OnRotate = func(oldname, newname string) {
+		// a slow hook which logs through the rotating writer
+		time.Sleep(time.Millisecond)
+		l.Infot("rotated", String("old", filepath.Base(oldname)))
+	}
+	l = New(opts)
+
+	const rotations = 64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < rotations; i++ {
+			atomic.StoreInt32(&n, int32(i))
+			l.Infot("entry", Int("seq", i))
+		}
+		assert.NoError(t, l.Close())
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("logging from the rotation hook deadlocked")
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "test-*.log"))
+	assert.NoError(t, err)
+	var rotated int
+	for _, name := range files {
+		content, err := os.ReadFile(name)
+		assert.NoError(t, err)
+		rotated += strings.Count(string(content), `"msg":"rotated"`)
+	}
+	assert.Equal(t, rotations-1, rotated)
+}
+
+func TestIsRotatedFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  string
+		expected bool
+	}{
+		{"app-20060102.log", "app-20060103.log", true},
+		{"app-20060102-15.log", "app-20060102-16.log", true},
+		{"app-20060102-2006-01-02T15-04-05.000.log", "app-20060103.log", true},
+		{"test-2006-01-02T15-04-05.000.log", "test.log", true},
+		{"app-20060102.txt", "app-20060103.log", false},
+		{"other-20060102.log", "app-20060103.log", false},
+		{"app-debug-20060102.log", "app-20060103.log", false},
+		{"app2-20060102.log", "app-20060103.log", false},
+		{"app-20060102.log", "app2-20060103.log", false},
+		{"app-20060102-2006-01-02T15-04-05.000.log.gz", "app-20060103.log", true},
+		{"app-20060102-15.log", "app-20060103.log", false},
+		{"app-2006010.log", "app-20060103.log", false},
+		{"app-20061302.log", "app-20060103.log", false},
+		{"app-20060102-backup.log", "app-20060103.log", false},
+		{"test-2006-01-02T15-04-05.log", "test.log", false},
+		{"test-20060102.log", "test.log", false},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			assert.Equal(t, v.expected, isRotatedFile(v.name, v.current))
+		})
+	}
+}
+
+type stepClock struct {
+	n int64
+}
+
+func (c *stepClock) Now() time.Time {
+	n := atomic.AddInt64(&c.n, 1)
+	return time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC).Add(time.Duration(n) * time.Second)
+}
+
+func (c *stepClock) NewTicker(d time.Duration) *time.Ticker { return time.NewTicker(d) }
+
+func TestRetentionPendingRotations(t *testing.T) {
+	dir := t.TempDir()
+	var (
+		mu      sync.Mutex
+		rotated = map[string]bool{}
+		errs    []string
+	)
+	release := make(chan struct{})
+	opts := NewOptions()
+	opts.DisableConsole = true
+	opts.DisableFile = false
+	opts.DisableRotate = false
+	opts.Output = dir
+	opts.Clock = &stepClock{}
+	opts.FilenameEncoder = func() string {
+		return "app-20060105.log"
+	}
+	opts.MaxSize = 1
+	opts.MaxBackups = 2
+	opts.Compress = true
+	opts.OnRotate = func(oldname, newname string) {
+		// block the mill goroutine until all the rotations are queued
+		<-release
+		mu.Lock()
+		defer mu.Unlock()
+		if _, err := os.Stat(oldname); err != nil {
+			errs = append(errs, "rotated file is missing: "+oldname)
+		}
+		if !strings.HasSuffix(oldname, compressSuffix) {
+			errs = append(errs, "rotated file is not compressed: "+oldname)
+		}
+		rotated[oldname] = true
+	}
+	opts.OnRemove = func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !rotated[name] {
+			errs = append(errs, "removed before rotated: "+name)
+		}
+	}
+	l := New(opts)
+
+	entry := strings.Repeat("x", 300*1024)
+	for i := 0; i < 20; i++ {
+		l.Infot(entry)
+	}
+	close(release)
+	assert.NoError(t, l.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Empty(t, errs)
+	assert.Equal(t, 6, len(rotated))
+	files, err := filepath.Glob(filepath.Join(dir, "app-20060105-*.log.gz"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(files))
+}
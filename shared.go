@@ -0,0 +1,185 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// sharedCheckInterval is the interval of checking whether the shared
+// logfile has been rotated by another process.
+var sharedCheckInterval = time.Second
+
+// openShared opens the shared logfile, created reports whether the logfile
+// is created by this process, only the creator writes the header. A shared
+// flock is held on the logfile while it's open, so that the rotated logfile
+// is not compressed before every process has switched to the new one, see
+// lockRotated.
+func (w *rotateWriter) openShared(filename string) (created bool, err error) {
+	var f *os.File
+	for {
+		f, created, err = openSharedFile(w.opts, filename)
+		if err != nil {
+			return false, fmt.Errorf("can't open shared log file: %s", err)
+		}
+		if err = rlockFile(f); err != nil {
+			_ = f.Close()
+			return false, fmt.Errorf("can't lock shared log file: %s", err)
+		}
+		// the logfile may be rotated before the flock is acquired
+		fi, ferr := f.Stat()
+		info, err := os.Stat(filename)
+		if ferr == nil && err == nil && os.SameFile(fi, info) {
+			break
+		}
+		_ = f.Close()
+	}
+	var size int64
+	if info, err := f.Stat(); err == nil {
+		size = info.Size()
+	}
+	w.setFile(f, filename, size, created)
+	w.lastCheck = w.clock.Now()
+	return created, nil
+}
+
+// openSharedFile opens or creates the shared logfile, created reports
+// whether the logfile is created by this call.
+func openSharedFile(opts *Options, filename string) (f *os.File, created bool, err error) {
+	f, err = openFile(opts, filename, os.O_APPEND|os.O_CREATE|os.O_EXCL|os.O_WRONLY)
+	if err == nil {
+		return f, true, nil
+	}
+	if !os.IsExist(err) {
+		return nil, false, err
+	}
+	f, err = openFile(opts, filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY)
+	return f, false, err
+}
+
+// lockRotated waits until the other processes have switched from the
+// rotated shared logfile to the new one, and returns the function to
+// release the exclusive flock of the rotated logfile. The processes hold a
+// shared flock on the logfile while it's open, so the exclusive flock is
+// acquired after they closed it. It gives up if the closing channel is
+// closed before that.
+func lockRotated(clock Clock, name string, closing <-chan struct{}) (unlock func(), ok bool) {
+	ticker := clock.NewTicker(durationOrDefault(sharedCheckInterval, 10*time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		unlock, locked, err := tryLockFile(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to lock rotated log file: %v\n", err)
+			return nil, false
+		}
+		if locked {
+			return unlock, true
+		}
+		select {
+		case _, open := <-closing:
+			if !open {
+				return nil, false
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkShared reopens the shared logfile if it has been rotated or removed
+// by another process, otherwise rotates it if it grows beyond MaxSize.
+func (w *rotateWriter) checkShared(writeLen int) error {
+	w.lastCheck = w.clock.Now()
+	rotated, err := w.sharedRotated()
+	if err != nil {
+		return err
+	}
+	if rotated {
+		if err = w.close(); err != nil {
+			return err
+		}
+		_, err = w.openShared(w.filename)
+		return err
+	}
+	if !w.opts.DisableRotate && w.size > 0 && w.size+int64(writeLen) > w.max() {
+		return w.rotate("")
+	}
+	return nil
+}
+
+// sharedRotated reports whether the path of the current logfile no longer
+// refers to the opened file, it also updates the size of the logfile.
+func (w *rotateWriter) sharedRotated() (bool, error) {
+	if err := w.flush(); err != nil {
+		return false, err
+	}
+	fi, err := w.file.Stat()
+	if err != nil {
+		return false, fmt.Errorf("error getting log file info: %s", err)
+	}
+	info, err := os.Stat(w.filename)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error getting log file info: %s", err)
+	}
+	w.size = fi.Size()
+	return !os.SameFile(fi, info), nil
+}
+
+// rotateShared rotates the shared logfile, the size-based rotation is
+// coordinated by a lock file, so that exactly one process renames the
+// logfile. When the filename changes, every process switches to the new
+// logfile, and the process which creates it runs the hooks and retention.
+func (w *rotateWriter) rotateShared(next string) error {
+	if next != "" {
+		if err := w.close(); err != nil {
+			return err
+		}
+		prev := w.filename
+		created, err := w.openShared(next)
+		if err == nil && created {
+			w.mill(prev, next)
+		}
+		return err
+	}
+
+	unlock, err := lockFile(w.opts, lockName(w.filename))
+	if err != nil {
+		return fmt.Errorf("can't lock log file: %s", err)
+	}
+	defer unlock()
+
+	// check again with the lock held, another process may have rotated it
+	rotated, err := w.sharedRotated()
+	if err != nil {
+		return err
+	}
+	if err = w.close(); err != nil {
+		return err
+	}
+	filename := w.filename
+	if rotated {
+		_, err = w.openShared(filename)
+		return err
+	}
+
+	backup := backupName(filename, w.backupTime())
+	if err = os.Rename(filename, backup); err != nil {
+		return fmt.Errorf("can't rename log file: %s", err)
+	}
+	if _, err = w.openShared(filename); err != nil {
+		return err
+	}
+	w.mill(backup, filename)
+	return nil
+}
+
+// lockName returns the name of the lock file of the logfile series, e.g.
+// .app.lock for app-20060102.log.
+func lockName(filename string) string {
+	stem, _, _ := logFileSeries(filename)
+	return filepath.Join(filepath.Dir(filename), "."+stem+".lock")
+}
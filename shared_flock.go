@@ -0,0 +1,58 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package log
+
+import (
+	"os"
+	"syscall"
+)
+
+// sharedFileSupported reports whether SharedFile is supported on this platform.
+const sharedFileSupported = true
+
+// lockFile acquires an exclusive flock on the named file, and returns the
+// function to release it.
+func lockFile(opts *Options, name string) (func(), error) {
+	f, err := openFile(opts, name, os.O_CREATE|os.O_RDWR)
+	if err != nil {
+		return nil, err
+	}
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		_ = f.Close()
+	}, nil
+}
+
+// tryLockFile acquires an exclusive flock on the named file without
+// blocking, locked is false if the file is locked by another file
+// descriptor.
+func tryLockFile(name string) (unlock func(), locked bool, err error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, false, err
+	}
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		_ = f.Close()
+		return nil, false, nil
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, false, err
+	}
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		_ = f.Close()
+	}, true, nil
+}
+
+// rlockFile acquires a shared flock on the file, which is released when the
+// file is closed.
+func rlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_SH)
+}
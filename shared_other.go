@@ -0,0 +1,27 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package log
+
+import (
+	"errors"
+	"os"
+)
+
+// sharedFileSupported reports whether SharedFile is supported on this platform.
+const sharedFileSupported = false
+
+// lockFile is not supported on this platform.
+func lockFile(_ *Options, _ string) (func(), error) {
+	return nil, errors.New("file lock is not supported on this platform")
+}
+
+// tryLockFile is not supported on this platform.
+func tryLockFile(_ string) (func(), bool, error) {
+	return nil, false, errors.New("file lock is not supported on this platform")
+}
+
+// rlockFile is not supported on this platform.
+func rlockFile(_ *os.File) error {
+	return errors.New("file lock is not supported on this platform")
+}
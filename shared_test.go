@@ -0,0 +1,174 @@
+package log
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedFile(t *testing.T) {
+	if !sharedFileSupported {
+		t.Skip("shared file is not supported on this platform")
+	}
+	tmp := sharedCheckInterval
+	sharedCheckInterval = 0
+	defer func() {
+		sharedCheckInterval = tmp
+	}()
+
+	dir := t.TempDir()
+	const (
+		workers = 2
+		entries = 1500
+	)
+	payload := strings.Repeat("x", 1000)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		// each logger has its own file descriptor, like a separate process
+		opts := NewOptions()
+		opts.DisableConsole = true
+		opts.DisableFile = false
+		opts.DisableRotate = false
+		opts.SharedFile = true
+		opts.MaxSize = 1
+		opts.Output = dir
+		opts.FilenameEncoder = func() string {
+			return "shared.log"
+		}
+		l := New(opts)
+
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for j := 0; j < entries; j++ {
+				l.Infot(payload, Int("worker", worker), Int("seq", j))
+			}
+			assert.NoError(t, l.Close())
+		}(i)
+	}
+	wg.Wait()
+
+	files, err := filepath.Glob(filepath.Join(dir, "shared*.log"))
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, len(files), 3)
+
+	seen := map[[2]int]bool{}
+	for _, name := range files {
+		f, err := os.Open(name)
+		assert.NoError(t, err)
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 4096), 4096)
+		for scanner.Scan() {
+			entry := struct {
+				Worker int `json:"worker"`
+				Seq    int `json:"seq"`
+			}{}
+			assert.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+			seen[[2]int{entry.Worker, entry.Seq}] = true
+		}
+		assert.NoError(t, scanner.Err())
+		_ = f.Close()
+	}
+	assert.Equal(t, workers*entries, len(seen))
+}
+
+func TestSharedCompress(t *testing.T) {
+	if !sharedFileSupported {
+		t.Skip("shared file is not supported on this platform")
+	}
+	tmp := sharedCheckInterval
+	sharedCheckInterval = 0
+	defer func() {
+		sharedCheckInterval = tmp
+	}()
+
+	newWriter := func(dir string) *rotateWriter {
+		opts := NewOptions()
+		opts.DisableRotate = false
+		opts.SharedFile = true
+		opts.Compress = true
+		opts.Output = dir
+		// the writers are not registered, like in separate processes
+		return newRotateWriter(opts, func() string { return "shared.log" }, nil)
+	}
+
+	t.Run("compress after the other processes switched", func(t *testing.T) {
+		dir := t.TempDir()
+		w1, w2 := newWriter(dir), newWriter(dir)
+		_, err := w1.Write([]byte("a1\n"))
+		assert.NoError(t, err)
+		_, err = w2.Write([]byte("b1\n"))
+		assert.NoError(t, err)
+
+		w1.mu.Lock()
+		assert.NoError(t, w1.rotate(""))
+		w1.mu.Unlock()
+
+		// w2 still writes to the rotated logfile
+		time.Sleep(50 * time.Millisecond)
+		backups, err := filepath.Glob(filepath.Join(dir, "shared-*.log"))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(backups))
+
+		// w2 switches to the new logfile on the next write
+		_, err = w2.Write([]byte("b2\n"))
+		assert.NoError(t, err)
+		assert.Eventually(t, func() bool {
+			_, err := os.Stat(backups[0] + compressSuffix)
+			return err == nil
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.NoError(t, w1.Close())
+		assert.NoError(t, w2.Close())
+
+		assert.NoFileExists(t, backups[0])
+		f, err := os.Open(backups[0] + compressSuffix)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer func() { _ = f.Close() }()
+		gz, err := gzip.NewReader(f)
+		if !assert.NoError(t, err) {
+			return
+		}
+		content, err := io.ReadAll(gz)
+		assert.NoError(t, err)
+		assert.Equal(t, "a1\nb1\n", string(content))
+		content, err = os.ReadFile(filepath.Join(dir, "shared.log"))
+		assert.NoError(t, err)
+		assert.Equal(t, "b2\n", string(content))
+	})
+
+	t.Run("leave uncompressed if the other processes don't switch", func(t *testing.T) {
+		dir := t.TempDir()
+		w1, w2 := newWriter(dir), newWriter(dir)
+		defer func() { _ = w2.Close() }()
+		_, err := w1.Write([]byte("a1\n"))
+		assert.NoError(t, err)
+		_, err = w2.Write([]byte("b1\n"))
+		assert.NoError(t, err)
+
+		w1.mu.Lock()
+		assert.NoError(t, w1.rotate(""))
+		w1.mu.Unlock()
+		assert.NoError(t, w1.Close())
+
+		backups, err := filepath.Glob(filepath.Join(dir, "shared-*.log"))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(backups))
+	})
+}
+
+func TestLockName(t *testing.T) {
+	assert.Equal(t, filepath.Join("logs", ".app.lock"), lockName(filepath.Join("logs", "app-20060102.log")))
+	assert.Equal(t, filepath.Join("logs", ".test.lock"), lockName(filepath.Join("logs", "test.log")))
+}
@@ -0,0 +1,196 @@
+//go:build go1.21
+// +build go1.21
+
+package log
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// slogHandler is a slog.Handler which writes through the cores of a Logger.
+type slogHandler struct {
+	core  zapcore.Core
+	name  string
+	clock Clock
+	// groups are the groups opened by WithGroup which have no attrs yet, an
+	// empty group is omitted
+	groups []string
+}
+
+// NewSlogHandler creates a slog.Handler which writes through the logger, the
+// records share the cores, the levels, the fields and the outputs of the
+// logger. The slog levels are mapped to the nearest level below, e.g.
+// slog.LevelWarn-1 is logged at InfoLevel, the levels above slog.LevelError
+// are logged at ErrorLevel.
+//
+// The groups are encoded as namespaces, the error values are encoded by
+// NamedErr and the fields of the ctx are added, see InfoContext. A zero
+// time of the record is replaced by the current time of the Clock of the
+// logger, the times are converted to its TimeZone.
+func NewSlogHandler(l *Logger) slog.Handler {
+	clock := l.clock
+	if clock == nil {
+		clock = zapcore.DefaultClock
+	}
+	return &slogHandler{core: l.log.Core(), name: l.log.Name(), clock: clock}
+}
+
+// SetSlogDefault makes the handler of the logger the slog.Default, so that
+// the records of the slog package functions and the standard log package are
+// written through the logger.
+func SetSlogDefault(l *Logger) {
+	slog.SetDefault(slog.New(NewSlogHandler(l)))
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.core.Enabled(slogLevel(level))
+}
+
+func (h *slogHandler) Handle(ctx context.Context, record slog.Record) error {
+	now := h.clock.Now()
+	ent := zapcore.Entry{
+		Level:      slogLevel(record.Level),
+		Time:       record.Time.In(now.Location()),
+		LoggerName: h.name,
+		Message:    record.Message,
+	}
+	if record.Time.IsZero() {
+		ent.Time = now
+	}
+	if record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		ent.Caller = zapcore.EntryCaller{
+			Defined:  true,
+			PC:       frame.PC,
+			File:     frame.File,
+			Line:     frame.Line,
+			Function: frame.Function,
+		}
+	}
+	ce := h.core.Check(ent, nil)
+	if ce == nil {
+		return nil
+	}
+
+	fields := append(extractContext(ctx), ContextFields(ctx)...)
+	attrs := make([]Field, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = appendSlogAttr(attrs, attr)
+		return true
+	})
+	if len(attrs) > 0 {
+		fields = append(fields, h.namespaces()...)
+		fields = append(fields, attrs...)
+	}
+	ce.Write(fields...)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var fields []Field
+	for _, attr := range attrs {
+		fields = appendSlogAttr(fields, attr)
+	}
+	if len(fields) == 0 {
+		return h
+	}
+	return &slogHandler{
+		core:  h.core.With(append(h.namespaces(), fields...)),
+		name:  h.name,
+		clock: h.clock,
+	}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	groups := make([]string, 0, len(h.groups)+1)
+	groups = append(groups, h.groups...)
+	return &slogHandler{
+		core:   h.core,
+		name:   h.name,
+		clock:  h.clock,
+		groups: append(groups, name),
+	}
+}
+
+// namespaces returns the namespace fields of the pending groups.
+func (h *slogHandler) namespaces() []Field {
+	fields := make([]Field, 0, len(h.groups))
+	for _, g := range h.groups {
+		fields = append(fields, Namespace(g))
+	}
+	return fields
+}
+
+// slogLevel maps the slog level to the nearest level below.
+func slogLevel(level slog.Level) Level {
+	switch {
+	case level < slog.LevelInfo:
+		return DebugLevel
+	case level < slog.LevelWarn:
+		return InfoLevel
+	case level < slog.LevelError:
+		return WarnLevel
+	}
+	return ErrorLevel
+}
+
+// appendSlogAttr appends the field of the attr, the empty attrs and groups
+// are omitted, the attrs of a group without key are inlined.
+func appendSlogAttr(fields []Field, attr slog.Attr) []Field {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+	switch attr.Value.Kind() {
+	case slog.KindGroup:
+		group := attr.Value.Group()
+		if len(group) == 0 {
+			return fields
+		}
+		if attr.Key == "" {
+			for _, a := range group {
+				fields = appendSlogAttr(fields, a)
+			}
+			return fields
+		}
+		return append(fields, Object(attr.Key, slogGroup(group)))
+	case slog.KindString:
+		return append(fields, String(attr.Key, attr.Value.String()))
+	case slog.KindInt64:
+		return append(fields, Int64(attr.Key, attr.Value.Int64()))
+	case slog.KindUint64:
+		return append(fields, Uint64(attr.Key, attr.Value.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, Float64(attr.Key, attr.Value.Float64()))
+	case slog.KindBool:
+		return append(fields, Bool(attr.Key, attr.Value.Bool()))
+	case slog.KindDuration:
+		return append(fields, Duration(attr.Key, attr.Value.Duration()))
+	case slog.KindTime:
+		return append(fields, Time(attr.Key, attr.Value.Time()))
+	}
+	if err, ok := attr.Value.Any().(error); ok {
+		return append(fields, NamedErr(attr.Key, err))
+	}
+	return append(fields, Any(attr.Key, attr.Value.Any()))
+}
+
+type slogGroup []slog.Attr
+
+func (g slogGroup) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	var fields []Field
+	for _, attr := range g {
+		fields = appendSlogAttr(fields, attr)
+	}
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+	return nil
+}
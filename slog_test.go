@@ -0,0 +1,108 @@
+//go:build go1.21
+// +build go1.21
+
+package log
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testLogValuer struct{ name string }
+
+func (v testLogValuer) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("name", v.name), slog.Int("len", len(v.name)))
+}
+
+func TestSlogHandler(t *testing.T) {
+	l := newContextTestLogger(t)
+	logger := slog.New(NewSlogHandler(l))
+
+	logger.Debug("debug", "user", "alice", "attempt", 2)
+	logger.With("service", "api").WithGroup("req").Info("grouped",
+		slog.String("method", "GET"),
+		slog.Group("empty"),
+		slog.Group("", slog.Int("inline", 1)),
+		slog.Any("user", testLogValuer{name: "bob"}),
+		slog.Duration("elapsed", 1500*time.Millisecond))
+	// the empty groups are omitted
+	logger.WithGroup("empty").Warn("warn")
+	logger.WithGroup("a").With("x", 1).WithGroup("b").Error("nested", "y", 2)
+	logger.Error("failed", "err", errors.New("boom"))
+	logger.Log(context.Background(), slog.LevelError+4, "critical")
+	logger.InfoContext(WithContextFields(context.Background(), String("request_id", "r1")), "ctx")
+
+	lines := readContextTestLogger(t, l)
+	assert.Equal(t, []string{
+		`{"level":"DEBUG","caller":"log/slog_test.go:27","msg":"debug","user":"alice","attempt":2}`,
+		`{"level":"INFO","caller":"log/slog_test.go:28","msg":"grouped","service":"api","req":{"method":"GET","inline":1,` +
+			`"user":{"name":"bob","len":3},"elapsed":1500}}`,
+		`{"level":"WARN","caller":"log/slog_test.go:35","msg":"warn"}`,
+		`{"level":"ERROR","caller":"log/slog_test.go:36","msg":"nested","a":{"x":1,"b":{"y":2}}}`,
+		`{"level":"ERROR","caller":"log/slog_test.go:37","msg":"failed","err":"boom"}`,
+		`{"level":"ERROR","caller":"log/slog_test.go:38","msg":"critical"}`,
+		`{"level":"INFO","caller":"log/slog_test.go:39","msg":"ctx","request_id":"r1"}`,
+	}, lines)
+}
+
+func TestSlogHandlerEnabled(t *testing.T) {
+	l := newContextTestLogger(t)
+	defer func() { _ = l.Close() }()
+	h := NewSlogHandler(l.WithValues(String("component", "db")))
+	assert.True(t, h.Enabled(context.Background(), slog.LevelDebug))
+
+	opts := NewOptions()
+	opts.ConsoleLevel = WarnLevel.String()
+	h = NewSlogHandler(New(opts))
+	assert.False(t, h.Enabled(context.Background(), slog.LevelInfo))
+	assert.True(t, h.Enabled(context.Background(), slog.LevelWarn))
+	assert.True(t, h.Enabled(context.Background(), slog.LevelError+4))
+
+	assert.Equal(t, DebugLevel, slogLevel(slog.LevelDebug-4))
+	assert.Equal(t, InfoLevel, slogLevel(slog.LevelWarn-1))
+	assert.Equal(t, WarnLevel, slogLevel(slog.LevelWarn))
+}
+
+func TestSetSlogDefault(t *testing.T) {
+	prev := slog.Default()
+	defer slog.SetDefault(prev)
+
+	l := newContextTestLogger(t)
+	SetSlogDefault(l.WithValues(String("component", "db")))
+	slog.Info("default")
+
+	lines := readContextTestLogger(t, l)
+	assert.Equal(t, 1, len(lines))
+	assert.True(t, strings.HasSuffix(lines[0], `"msg":"default","component":"db"}`), lines[0])
+}
+
+func TestSlogHandlerTimeZone(t *testing.T) {
+	opts := NewOptions()
+	opts.DisableConsole = true
+	opts.DisableFile = false
+	opts.DisableFileCaller = true
+	opts.Output = t.TempDir()
+	opts.TimeEncoder = DefaultTimeEncoder
+	opts.TimeZone = "UTC"
+	opts.Clock = fixedClock{t: time.Date(2006, 1, 2, 23, 30, 0, 0, time.FixedZone("CST", 8*3600))}
+	l := New(opts)
+	h := NewSlogHandler(l.Named("slog"))
+
+	// a zero time is replaced by the time of the clock
+	assert.NoError(t, h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "zero", 0)))
+	record := slog.NewRecord(time.Date(2006, 1, 3, 8, 0, 0, 0, time.FixedZone("JST", 9*3600)), slog.LevelInfo, "record", 0)
+	assert.NoError(t, h.Handle(context.Background(), record))
+	l.Infot("native")
+
+	assert.Equal(t, []string{
+		`{"level":"INFO","time":"2006-01-02 15:30:00.000","logger":"slog","msg":"zero"}`,
+		`{"level":"INFO","time":"2006-01-02 23:00:00.000","logger":"slog","msg":"record"}`,
+		`{"level":"INFO","time":"2006-01-02 15:30:00.000","msg":"native"}`,
+	}, readContextTestLogger(t, l))
+}
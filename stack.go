@@ -0,0 +1,176 @@
+package log
+
+import (
+	"os"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// Stacktrace modes.
+const (
+	// StacktraceFull captures the frames from the caller to the goroutine
+	// entry, including the runtime frames.
+	StacktraceFull = "full"
+	// StacktraceTrimmed captures the frames from the caller without the
+	// frames of this package, zap and the runtime.
+	StacktraceTrimmed = "trimmed"
+)
+
+// skipStackKey is the key of the SkipStack field.
+const skipStackKey = "log.skip-stack"
+
+var (
+	_stackPool = buffer.NewPool()
+	// _stackErrorOutput is the ErrorOutput of the entries written by the
+	// stackCore, which is the default of zap.
+	_stackErrorOutput = zapcore.Lock(os.Stderr)
+	// _pkgPrefix is the function name prefix of this package, e.g.
+	// github.com/shipengqi/log.
+	_pkgPrefix = func() string {
+		name := runtime.FuncForPC(reflect.ValueOf(New).Pointer()).Name()
+		return name[:strings.LastIndexByte(name, '.')+1]
+	}()
+)
+
+// SkipStack returns a field which disables the stacktrace of the entry.
+// It is not encoded.
+func SkipStack() Field {
+	return Field{Key: skipStackKey, Type: zapcore.SkipType}
+}
+
+// stackCore is a zapcore.Core that captures the stacktrace of the entries
+// at or above the level. It wraps the tee of the console and file cores, so
+// that the stacktrace is captured once for all of them.
+type stackCore struct {
+	zapcore.Core
+	level     Level
+	trimmed   bool
+	maxFrames int
+}
+
+// newStackCore wraps the core with the stacktrace policy of the options, the
+// core is returned as is if the StacktraceLevel is not set.
+func newStackCore(opts *Options, core zapcore.Core) zapcore.Core {
+	if opts.StacktraceLevel == "" {
+		return core
+	}
+	var level Level
+	if err := level.Set(strings.ToLower(opts.StacktraceLevel)); err != nil {
+		return core
+	}
+	return &stackCore{
+		Core:      core,
+		level:     level,
+		trimmed:   opts.StacktraceMode == StacktraceTrimmed,
+		maxFrames: opts.MaxStackFrames,
+	}
+}
+
+func (c *stackCore) With(fields []Field) zapcore.Core {
+	clone := *c
+	clone.Core = c.Core.With(fields)
+	return &clone
+}
+
+func (c *stackCore) Check(ent zapcore.Entry, ce *CheckedEntry) *CheckedEntry {
+	if ent.Level < c.level || ent.Stack != "" {
+		return c.Core.Check(ent, ce)
+	}
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write captures the stacktrace unless the fields have SkipStack, then
+// writes the entry to the cores which accept it.
+func (c *stackCore) Write(ent zapcore.Entry, fields []Field) error {
+	if !hasSkipStack(fields) {
+		ent.Stack = captureStack(c.trimmed, c.maxFrames)
+	}
+	if ce := c.Core.Check(ent, nil); ce != nil {
+		ce.ErrorOutput = _stackErrorOutput
+		ce.Write(fields...)
+	}
+	return nil
+}
+
+func hasSkipStack(fields []Field) bool {
+	for i := range fields {
+		if fields[i].Type == zapcore.SkipType && fields[i].Key == skipStackKey {
+			return true
+		}
+	}
+	return false
+}
+
+// captureStack returns the stacktrace of the caller in the format of zap,
+// the frames of the logging calls are always skipped. If trimmed is true,
+// the frames of this package, zap and the runtime are skipped. If maxFrames
+// is positive, the frames beyond it are omitted.
+func captureStack(trimmed bool, maxFrames int) string {
+	pcs := make([]uintptr, 64)
+	for {
+		n := runtime.Callers(2, pcs)
+		if n < len(pcs) {
+			pcs = pcs[:n]
+			break
+		}
+		pcs = make([]uintptr, len(pcs)*2)
+	}
+
+	buf := _stackPool.Get()
+	defer buf.Free()
+	frames := runtime.CallersFrames(pcs)
+	var (
+		caller  bool
+		written int
+		omitted int
+	)
+	for more := true; more; {
+		var frame runtime.Frame
+		frame, more = frames.Next()
+		internal := isInternalFrame(frame)
+		// skips the logging calls before the caller
+		if !caller && internal {
+			continue
+		}
+		caller = true
+		if trimmed && (internal || strings.HasPrefix(frame.Function, "runtime.")) {
+			continue
+		}
+		if maxFrames > 0 && written >= maxFrames {
+			omitted++
+			continue
+		}
+		if written > 0 {
+			buf.AppendByte('\n')
+		}
+		buf.AppendString(frame.Function)
+		buf.AppendString("\n\t")
+		buf.AppendString(frame.File)
+		buf.AppendByte(':')
+		buf.AppendInt(int64(frame.Line))
+		written++
+	}
+	if omitted > 0 {
+		buf.AppendString("\n...")
+		buf.AppendString(strconv.Itoa(omitted))
+		buf.AppendString(" more frames")
+	}
+	return buf.String()
+}
+
+// isInternalFrame reports whether the frame is a frame of zap, log/slog or
+// this package, the frames of the tests of this package are not internal.
+func isInternalFrame(frame runtime.Frame) bool {
+	if strings.HasPrefix(frame.Function, "go.uber.org/zap") || strings.HasPrefix(frame.Function, "log/slog.") {
+		return true
+	}
+	return strings.HasPrefix(frame.Function, _pkgPrefix) && !strings.HasSuffix(frame.File, "_test.go")
+}
@@ -0,0 +1,121 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func newTestStackLogger(opts *Options, out *bytes.Buffer) *zap.Logger {
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+		MessageKey:    "msg",
+		StacktraceKey: "stack",
+		LineEnding:    "\n",
+	}), zapcore.AddSync(out), DebugLevel)
+	return zap.New(newStackCore(opts, core))
+}
+
+func entryStack(t *testing.T, out *bytes.Buffer) string {
+	entry := struct {
+		Stack string `json:"stack"`
+	}{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	out.Reset()
+	return entry.Stack
+}
+
+func TestStackCore(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	t.Run("full", func(t *testing.T) {
+		opts := NewOptions()
+		opts.StacktraceLevel = "error"
+		l := newTestStackLogger(opts, out)
+
+		l.Warn("no stack")
+		assert.Equal(t, "", entryStack(t, out))
+
+		l.Error("stack")
+		stack := entryStack(t, out)
+		assert.True(t, strings.HasPrefix(stack, "github.com/shipengqi/log.TestStackCore.func1\n\t"), stack)
+		assert.Contains(t, stack, "log/stack_test.go:")
+		assert.Contains(t, stack, "testing.tRunner")
+		assert.Contains(t, stack, "runtime.goexit")
+		assert.NotContains(t, stack, "go.uber.org/zap")
+
+		l.Error("skip", SkipStack())
+		assert.Equal(t, `{"msg":"skip"}`+"\n", out.String())
+		out.Reset()
+	})
+
+	t.Run("trimmed", func(t *testing.T) {
+		opts := NewOptions()
+		opts.StacktraceLevel = "warn"
+		opts.StacktraceMode = StacktraceTrimmed
+		l := newTestStackLogger(opts, out)
+
+		l.Warn("stack")
+		stack := entryStack(t, out)
+		assert.True(t, strings.HasPrefix(stack, "github.com/shipengqi/log.TestStackCore.func2\n\t"), stack)
+		assert.Contains(t, stack, "testing.tRunner")
+		assert.NotContains(t, stack, "runtime.")
+	})
+
+	t.Run("max frames", func(t *testing.T) {
+		opts := NewOptions()
+		opts.StacktraceLevel = "error"
+		opts.MaxStackFrames = 1
+		l := newTestStackLogger(opts, out)
+
+		l.Error("stack")
+		lines := strings.Split(entryStack(t, out), "\n")
+		assert.Equal(t, 3, len(lines))
+		assert.Equal(t, "github.com/shipengqi/log.TestStackCore.func3", lines[0])
+		assert.Equal(t, "...2 more frames", lines[2])
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		opts := NewOptions()
+		opts.StacktraceLevel = "errors"
+		opts.StacktraceMode = "short"
+		errs := opts.Validate()
+		assert.Equal(t, 2, len(errs))
+		assert.Equal(t, "unrecognized level: \"errors\"", errs[0].Error())
+		assert.Equal(t, "unrecognized stacktrace mode: \"short\"", errs[1].Error())
+	})
+}
+
+func TestStackCoreTee(t *testing.T) {
+	newCore := func(out *bytes.Buffer, level Level) zapcore.Core {
+		return zapcore.NewCore(zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+			MessageKey:    "msg",
+			StacktraceKey: "stack",
+			LineEnding:    "\n",
+		}), zapcore.AddSync(out), level)
+	}
+	console, file := &bytes.Buffer{}, &bytes.Buffer{}
+	opts := NewOptions()
+	opts.StacktraceLevel = "warn"
+	l := zap.New(newStackCore(opts, zapcore.NewTee(newCore(console, ErrorLevel), newCore(file, DebugLevel))))
+
+	// the entry is only written to the cores which accept it
+	l.Warn("warn")
+	assert.Equal(t, "", console.String())
+	assert.NotEqual(t, "", entryStack(t, file))
+
+	// the stacktrace is captured once for the cores
+	l.Error("error")
+	assert.Equal(t, console.String(), file.String())
+	stack := entryStack(t, console)
+	assert.True(t, strings.HasPrefix(stack, "github.com/shipengqi/log.TestStackCoreTee\n\t"), stack)
+	file.Reset()
+
+	l.Info("info")
+	assert.Equal(t, "", console.String())
+	assert.Equal(t, `{"msg":"info"}`+"\n", file.String())
+}
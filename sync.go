@@ -0,0 +1,66 @@
+package log
+
+import (
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Sync policies of the log file.
+const (
+	// SyncPolicyNone leaves the flushing of the log file to the operating
+	// system, the log file is only committed to disk by Flush and Close.
+	SyncPolicyNone = "none"
+	// SyncPolicyWrite commits the log file to disk after every write.
+	SyncPolicyWrite = "write"
+	// SyncPolicyInterval commits the log file to disk every SyncInterval.
+	SyncPolicyInterval = "interval"
+	// SyncPolicyLevel commits the log file to disk after writing an entry
+	// at or above SyncLevel.
+	SyncPolicyLevel = "level"
+)
+
+const (
+	// DefaultSyncInterval is the interval of SyncPolicyInterval when
+	// SyncInterval is not set.
+	DefaultSyncInterval = time.Second
+	// DefaultFlushInterval is the interval of flushing the write buffer when
+	// FlushInterval is not set.
+	DefaultFlushInterval = time.Second
+)
+
+func durationOrDefault(d, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+	return d
+}
+
+// syncCore is a zapcore.Core that commits the log file to disk after
+// writing an entry at or above the level.
+type syncCore struct {
+	zapcore.Core
+	syncer zapcore.WriteSyncer
+	level  Level
+}
+
+func (c *syncCore) With(fields []Field) zapcore.Core {
+	return &syncCore{Core: c.Core.With(fields), syncer: c.syncer, level: c.level}
+}
+
+func (c *syncCore) Check(ent zapcore.Entry, ce *CheckedEntry) *CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *syncCore) Write(ent zapcore.Entry, fields []Field) error {
+	if err := c.Core.Write(ent, fields); err != nil {
+		return err
+	}
+	if ent.Level >= c.level {
+		return c.syncer.Sync()
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type countSyncer struct {
+	syncs int
+}
+
+func (s *countSyncer) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (s *countSyncer) Sync() error {
+	s.syncs++
+	return nil
+}
+
+func TestSyncPolicy(t *testing.T) {
+	t.Run("buffered writes", func(t *testing.T) {
+		dir := t.TempDir()
+		opts := NewOptions()
+		opts.DisableConsole = true
+		opts.DisableFile = false
+		opts.BufferSize = 4096
+		opts.Output = dir
+		opts.FilenameEncoder = func() string {
+			return "test.log"
+		}
+		l := New(opts)
+		l.Info("Hello, world!")
+
+		content, err := os.ReadFile(filepath.Join(dir, "test.log"))
+		assert.NoError(t, err)
+		assert.Empty(t, content)
+
+		assert.NoError(t, l.Flush())
+		content, err = os.ReadFile(filepath.Join(dir, "test.log"))
+		assert.NoError(t, err)
+		assert.Contains(t, string(content), "Hello, world!")
+
+		l.Info("Hello again!")
+		assert.NoError(t, l.Close())
+		content, err = os.ReadFile(filepath.Join(dir, "test.log"))
+		assert.NoError(t, err)
+		assert.Contains(t, string(content), "Hello again!")
+	})
+
+	t.Run("sync at level", func(t *testing.T) {
+		inner, _ := observer.New(DebugLevel)
+		syncer := &countSyncer{}
+		core := (&syncCore{Core: inner, syncer: syncer, level: WarnLevel}).With([]Field{String("k", "v")})
+
+		for _, lvl := range []Level{DebugLevel, InfoLevel, WarnLevel, ErrorLevel} {
+			if ce := core.Check(zapcore.Entry{Level: lvl}, nil); ce != nil {
+				ce.Write()
+			}
+		}
+		assert.Equal(t, 2, syncer.syncs)
+	})
+
+	t.Run("invalid sync policy", func(t *testing.T) {
+		opts := NewOptions()
+		opts.SyncPolicy = "always"
+		errs := opts.Validate()
+
+		assert.Equal(t, 1, len(errs))
+		assert.Equal(t, "unrecognized sync policy: \"always\"", errs[0].Error())
+	})
+}
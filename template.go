@@ -0,0 +1,352 @@
+package log
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var templateColors = map[string]string{
+	"red":     colorRed,
+	"green":   colorGreen,
+	"yellow":  colorYellow,
+	"blue":    colorBlue,
+	"magenta": colorMagenta,
+	"cyan":    colorCyan,
+	"bold":    colorBold,
+	"faint":   colorFaint,
+}
+
+// templateSegment is a literal text, a placeholder or a conditional segment
+// of a console template.
+type templateSegment struct {
+	literal string
+	// field is the name of the placeholder
+	field string
+	// layout is the time layout of the time placeholder
+	layout string
+	// width pads the value with spaces, a negative width aligns it right
+	width int
+	// max truncates the value, 0 means no truncation
+	max int
+	// colors are the escape codes of the value, levelColor colors the
+	// value by the level
+	colors     []string
+	levelColor bool
+	// cond are the segments which are omitted if any of the placeholders
+	// is empty
+	cond []templateSegment
+}
+
+// templateEncoder is a zapcore.Encoder which encodes the entries with a
+// console template, e.g.
+//
+//	{time:15:04:05} {level:5} {? [{logger}]} {caller|faint} {msg} {fields}
+//
+// A placeholder is {name[:spec][|directive]...}, the names are time, level,
+// logger, caller, function, msg, fields and stack. The spec of the time is a
+// time layout, the spec of the others is [-]width[.max], which pads the
+// value to width and truncates it to max characters, a negative width aligns
+// the value right. The directives are the colors red, green, yellow, blue,
+// magenta, cyan, bold, faint and level, which colors the value by the level.
+// A conditional segment {? ...} is omitted if any placeholder in it is
+// empty. {{ and }} are the literal braces.
+//
+// The fields are encoded in logfmt. The stacktrace is encoded on the next
+// lines if the template has no stack placeholder.
+type templateEncoder struct {
+	// logfmtEncoder encodes the context fields
+	*logfmtEncoder
+	segments []templateSegment
+	hasStack bool
+	color    bool
+}
+
+// NewTemplateEncoder creates an encoder which encodes the entries with the
+// console template. The time, level, caller and stack placeholders are
+// empty if the corresponding keys of the cfg are empty.
+func NewTemplateEncoder(cfg zapcore.EncoderConfig, template string, color bool) (zapcore.Encoder, error) {
+	segments, err := parseTemplate(template)
+	if err != nil {
+		return nil, err
+	}
+	return &templateEncoder{
+		logfmtEncoder: NewLogfmtEncoder(cfg).(*logfmtEncoder),
+		segments:      segments,
+		hasStack:      strings.Contains(template, "{stack"),
+		color:         color,
+	}, nil
+}
+
+func (enc *templateEncoder) Clone() zapcore.Encoder {
+	return &templateEncoder{
+		logfmtEncoder: enc.logfmtEncoder.Clone().(*logfmtEncoder),
+		segments:      enc.segments,
+		hasStack:      enc.hasStack,
+		color:         enc.color,
+	}
+}
+
+func (enc *templateEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	fe := enc.logfmtEncoder.Clone().(*logfmtEncoder)
+	for i := range fields {
+		fields[i].AddTo(fe)
+	}
+	values := templateValues{enc: enc, ent: ent, fields: fe.buf.String()}
+	fe.buf.Free()
+
+	buf := _logfmtPool.Get()
+	enc.render(buf, enc.segments, &values)
+	if ent.Stack != "" && enc.StacktraceKey != "" && !enc.hasStack {
+		buf.AppendString(enc.LineEnding)
+		buf.AppendString(ent.Stack)
+	}
+	buf.AppendString(enc.LineEnding)
+	return buf, nil
+}
+
+// render appends the segments, it reports whether all the placeholders are
+// not empty.
+func (enc *templateEncoder) render(buf *buffer.Buffer, segments []templateSegment, values *templateValues) bool {
+	full := true
+	for i := range segments {
+		seg := &segments[i]
+		switch {
+		case seg.cond != nil:
+			cond := _logfmtPool.Get()
+			if enc.render(cond, seg.cond, values) {
+				_, _ = buf.Write(cond.Bytes())
+			}
+			cond.Free()
+		case seg.field != "":
+			val := values.get(seg)
+			if val == "" {
+				full = false
+			}
+			enc.appendValue(buf, seg, val, values.ent.Level)
+		default:
+			buf.AppendString(seg.literal)
+		}
+	}
+	return full
+}
+
+func (enc *templateEncoder) appendValue(buf *buffer.Buffer, seg *templateSegment, val string, lvl Level) {
+	if seg.max > 0 && utf8.RuneCountInString(val) > seg.max {
+		val = string([]rune(val)[:seg.max])
+	}
+	width := seg.width
+	if width < 0 {
+		for n := utf8.RuneCountInString(val); n < -width; n++ {
+			buf.AppendByte(' ')
+		}
+	}
+	colors := seg.colors
+	if seg.levelColor {
+		colors = append([]string{levelColor(lvl)}, colors...)
+	}
+	if enc.color && len(colors) > 0 && val != "" {
+		for _, c := range colors {
+			buf.AppendString(c)
+		}
+		buf.AppendString(val)
+		buf.AppendString(colorReset)
+	} else {
+		buf.AppendString(val)
+	}
+	appendPadding(buf, val, width)
+}
+
+// templateValues encodes the values of the placeholders of an entry.
+type templateValues struct {
+	enc    *templateEncoder
+	ent    zapcore.Entry
+	fields string
+}
+
+func (v *templateValues) get(seg *templateSegment) string {
+	enc, ent := v.enc, v.ent
+	switch seg.field {
+	case "time":
+		if enc.TimeKey == "" || ent.Time.IsZero() {
+			return ""
+		}
+		if seg.layout != "" {
+			return ent.Time.Format(seg.layout)
+		}
+		if enc.EncodeTime == nil {
+			return ent.Time.Format("2006-01-02T15:04:05.000Z0700")
+		}
+		return encodeString(func(arr zapcore.PrimitiveArrayEncoder) {
+			enc.EncodeTime(ent.Time, arr)
+		}, "")
+	case "level":
+		if enc.LevelKey == "" || enc.EncodeLevel == nil {
+			return ""
+		}
+		return encodeString(func(arr zapcore.PrimitiveArrayEncoder) {
+			enc.EncodeLevel(ent.Level, arr)
+		}, ent.Level.CapitalString())
+	case "logger":
+		return ent.LoggerName
+	case "caller":
+		if enc.CallerKey == "" || !ent.Caller.Defined || enc.EncodeCaller == nil {
+			return ""
+		}
+		return encodeString(func(arr zapcore.PrimitiveArrayEncoder) {
+			enc.EncodeCaller(ent.Caller, arr)
+		}, ent.Caller.TrimmedPath())
+	case "function":
+		return ent.Caller.Function
+	case "msg":
+		return ent.Message
+	case "fields":
+		return v.fields
+	case "stack":
+		if enc.StacktraceKey == "" {
+			return ""
+		}
+		return ent.Stack
+	}
+	return ""
+}
+
+// parseTemplate parses the console template into segments.
+func parseTemplate(template string) ([]templateSegment, error) {
+	segments, rest, err := parseSegments(template, false)
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("unexpected '}' in console template at %d", len(template)-len(rest))
+	}
+	return segments, nil
+}
+
+// parseSegments parses the segments until the end of the template, or the
+// closing brace of the conditional segment if cond is true. It returns the
+// unparsed template after the closing brace.
+func parseSegments(template string, cond bool) ([]templateSegment, string, error) {
+	var (
+		segments []templateSegment
+		literal  strings.Builder
+	)
+	flush := func() {
+		if literal.Len() > 0 {
+			segments = append(segments, templateSegment{literal: literal.String()})
+			literal.Reset()
+		}
+	}
+	s := template
+	for s != "" {
+		switch {
+		case strings.HasPrefix(s, "{{"), strings.HasPrefix(s, "}}"):
+			literal.WriteByte(s[0])
+			s = s[2:]
+		case strings.HasPrefix(s, "{?"):
+			flush()
+			children, rest, err := parseSegments(s[2:], true)
+			if err != nil {
+				return nil, "", err
+			}
+			if children == nil {
+				children = []templateSegment{}
+			}
+			segments = append(segments, templateSegment{cond: children})
+			s = rest
+		case s[0] == '{':
+			end := strings.IndexByte(s, '}')
+			if end < 0 {
+				return nil, "", fmt.Errorf("unclosed placeholder in console template: %q", s)
+			}
+			flush()
+			seg, err := parsePlaceholder(s[1:end])
+			if err != nil {
+				return nil, "", err
+			}
+			segments = append(segments, seg)
+			s = s[end+1:]
+		case s[0] == '}':
+			if cond {
+				flush()
+				return segments, s[1:], nil
+			}
+			flush()
+			return segments, s, nil
+		default:
+			literal.WriteByte(s[0])
+			s = s[1:]
+		}
+	}
+	if cond {
+		return nil, "", fmt.Errorf("unclosed conditional segment in console template: %q", template)
+	}
+	flush()
+	return segments, "", nil
+}
+
+// parsePlaceholder parses the placeholder without the braces, e.g.
+// level:5|level.
+func parsePlaceholder(s string) (templateSegment, error) {
+	directives := strings.Split(s, "|")
+	name, spec := directives[0], ""
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		name, spec = name[:i], name[i+1:]
+	}
+
+	seg := templateSegment{field: name}
+	switch name {
+	case "time":
+		seg.layout = spec
+	case "level", "logger", "caller", "function", "msg", "fields", "stack":
+		if err := parseTemplateSpec(&seg, spec); err != nil {
+			return seg, err
+		}
+	default:
+		return seg, fmt.Errorf("unrecognized placeholder in console template: %q", name)
+	}
+
+	for _, d := range directives[1:] {
+		if d == "level" {
+			seg.levelColor = true
+			continue
+		}
+		c, ok := templateColors[d]
+		if !ok {
+			return seg, fmt.Errorf("unrecognized color in console template: %q", d)
+		}
+		seg.colors = append(seg.colors, c)
+	}
+	// colors the level by default
+	if name == "level" && len(directives) == 1 {
+		seg.levelColor = true
+	}
+	return seg, nil
+}
+
+// parseTemplateSpec parses the [-]width[.max] spec.
+func parseTemplateSpec(seg *templateSegment, spec string) error {
+	if spec == "" {
+		return nil
+	}
+	width, maxLen := spec, ""
+	if i := strings.IndexByte(spec, '.'); i >= 0 {
+		width, maxLen = spec[:i], spec[i+1:]
+	}
+	var err error
+	if width != "" {
+		if seg.width, err = strconv.Atoi(width); err != nil {
+			return fmt.Errorf("invalid width in console template: %q", spec)
+		}
+	}
+	if maxLen != "" {
+		if seg.max, err = strconv.Atoi(maxLen); err != nil || seg.max < 0 {
+			return fmt.Errorf("invalid truncation in console template: %q", spec)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestTemplateEncoder(t *testing.T) {
+	cfg := zapcore.EncoderConfig{
+		TimeKey:       "time",
+		LevelKey:      "level",
+		CallerKey:     "caller",
+		MessageKey:    "msg",
+		StacktraceKey: "stack",
+		LineEnding:    "\n",
+		EncodeLevel:   zapcore.CapitalLevelEncoder,
+		EncodeCaller:  zapcore.ShortCallerEncoder,
+	}
+	ent := zapcore.Entry{
+		Level:   WarnLevel,
+		Time:    time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+		Message: "hello world",
+		Caller:  zapcore.NewEntryCaller(0, "/src/app/main.go", 10, true),
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		color    bool
+		ent      zapcore.Entry
+		expected string
+	}{
+		{
+			"layout",
+			"{time:15:04:05} {level:5}{? [{logger}]} {caller:-16} {msg} {fields}",
+			false,
+			ent,
+			"15:04:05 WARN    app/main.go:10 hello world id=1 user=foo\n",
+		},
+		{
+			"conditional segment",
+			"{level:5}{? [{logger}]} {msg:.5}",
+			false,
+			zapcore.Entry{Level: InfoLevel, LoggerName: "main", Message: "hello world"},
+			"INFO  [main] hello\n",
+		},
+		{
+			"braces",
+			"{{{msg}}}",
+			false,
+			ent,
+			"{hello world}\n",
+		},
+		{
+			"color",
+			"{level} {msg|bold|red}",
+			true,
+			ent,
+			colorYellow + "WARN" + colorReset + " " + colorBold + colorRed + "hello world" + colorReset + "\n",
+		},
+		{
+			"stack",
+			"{msg}",
+			false,
+			zapcore.Entry{Message: "failed", Stack: "main.run\n\t/src/app/main.go:10"},
+			"failed\nmain.run\n\t/src/app/main.go:10\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc, err := NewTemplateEncoder(cfg, tt.template, tt.color)
+			assert.NoError(t, err)
+			enc.AddInt("id", 1)
+			buf, err := enc.EncodeEntry(tt.ent, []Field{String("user", "foo")})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
+
+func TestParseTemplate(t *testing.T) {
+	for template, expected := range map[string]string{
+		"{msg":           "unclosed placeholder in console template: \"{msg\"",
+		"{? [{logger}]":  "unclosed conditional segment in console template: \" [{logger}]\"",
+		"{message}":      "unrecognized placeholder in console template: \"message\"",
+		"{msg|purple}":   "unrecognized color in console template: \"purple\"",
+		"{level:x}":      "invalid width in console template: \"x\"",
+		"{level:5.-1}":   "invalid truncation in console template: \"5.-1\"",
+		"{msg} } {time}": "unexpected '}' in console template at 6",
+	} {
+		_, err := parseTemplate(template)
+		assert.EqualError(t, err, expected, template)
+	}
+
+	opts := NewOptions()
+	opts.ConsoleTemplate = "{msg"
+	errs := opts.Validate()
+	assert.Equal(t, 1, len(errs))
+}
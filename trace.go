@@ -0,0 +1,357 @@
+package log
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// W3CTraceExtractor is the name of the built-in ContextExtractor, which
+// extracts the trace fields from the TraceParent of the ctx.
+const W3CTraceExtractor = "w3c"
+
+// The headers of the W3C trace context.
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+)
+
+// maxTraceStateMembers is the max list members of a tracestate.
+const maxTraceStateMembers = 32
+
+// ContextExtractor extracts the fields from the ctx, which are added to the
+// entries logged by the Context methods, e.g. InfoContext.
+type ContextExtractor func(ctx context.Context) []Field
+
+// extractors is the process-wide registry of the ContextExtractors.
+var extractors = &extractorRegistry{
+	entries: []namedExtractor{{name: W3CTraceExtractor, extract: extractTraceParent}},
+}
+
+type namedExtractor struct {
+	name    string
+	extract ContextExtractor
+}
+
+type extractorRegistry struct {
+	mu      sync.RWMutex
+	entries []namedExtractor
+}
+
+// RegisterContextExtractor registers the extractor under the name, the
+// extractor registered under the same name is replaced. The extractors are
+// called in the order of registration.
+func RegisterContextExtractor(name string, extractor ContextExtractor) {
+	if extractor == nil {
+		UnregisterContextExtractor(name)
+		return
+	}
+	extractors.mu.Lock()
+	defer extractors.mu.Unlock()
+
+	for i := range extractors.entries {
+		if extractors.entries[i].name == name {
+			extractors.entries[i].extract = extractor
+			return
+		}
+	}
+	extractors.entries = append(extractors.entries, namedExtractor{name: name, extract: extractor})
+}
+
+// UnregisterContextExtractor removes the extractor registered under the
+// name, e.g. W3CTraceExtractor.
+func UnregisterContextExtractor(name string) {
+	extractors.mu.Lock()
+	defer extractors.mu.Unlock()
+
+	for i := range extractors.entries {
+		if extractors.entries[i].name == name {
+			extractors.entries = append(extractors.entries[:i:i], extractors.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// extractContext returns the fields extracted from the ctx by the registered
+// extractors.
+func extractContext(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	extractors.mu.RLock()
+	defer extractors.mu.RUnlock()
+
+	var fields []Field
+	for _, e := range extractors.entries {
+		fields = append(fields, e.extract(ctx)...)
+	}
+	return fields
+}
+
+// SpanContext is the span context of a tracer. The span contexts of
+// OpenTelemetry and OpenTracing tracers, which have TraceID() and SpanID()
+// methods returning a string or a fmt.Stringer, are adapted by
+// SpanContextExtractor without importing their SDKs.
+type SpanContext interface {
+	TraceID() string
+	SpanID() string
+	IsSampled() bool
+}
+
+// SpanContextExtractor returns a ContextExtractor which extracts the trace
+// fields from the span context returned by the fn, e.g.
+//
+//	log.RegisterContextExtractor("otel", log.SpanContextExtractor(func(ctx context.Context) interface{} {
+//		return trace.SpanContextFromContext(ctx)
+//	}))
+//
+// The span context is a SpanContext, or has TraceID() and SpanID() methods
+// returning a string or a fmt.Stringer, an IsSampled() bool method and an
+// optional IsValid() bool method.
+func SpanContextExtractor(fn func(ctx context.Context) interface{}) ContextExtractor {
+	return func(ctx context.Context) []Field {
+		sc, ok := spanContextOf(fn(ctx))
+		if !ok {
+			return nil
+		}
+		flags := "00"
+		if sc.IsSampled() {
+			flags = "01"
+		}
+		return traceFields(sc.TraceID(), sc.SpanID(), flags)
+	}
+}
+
+// spanContextOf adapts the v to a SpanContext, it returns false if the v has
+// no valid trace id.
+func spanContextOf(v interface{}) (SpanContext, bool) {
+	if v == nil {
+		return nil, false
+	}
+	sc, ok := v.(SpanContext)
+	if !ok {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return nil, false
+		}
+		if valid, ok := callBool(rv, "IsValid"); ok && !valid {
+			return nil, false
+		}
+		traceID, ok := callString(rv, "TraceID")
+		if !ok {
+			return nil, false
+		}
+		spanID, _ := callString(rv, "SpanID")
+		sampled, _ := callBool(rv, "IsSampled")
+		sc = reflectSpanContext{traceID: traceID, spanID: spanID, sampled: sampled}
+	}
+	if isZeroID(sc.TraceID()) {
+		return nil, false
+	}
+	return sc, true
+}
+
+type reflectSpanContext struct {
+	traceID string
+	spanID  string
+	sampled bool
+}
+
+func (sc reflectSpanContext) TraceID() string { return sc.traceID }
+
+func (sc reflectSpanContext) SpanID() string { return sc.spanID }
+
+func (sc reflectSpanContext) IsSampled() bool { return sc.sampled }
+
+// callString calls the method without arguments of the v, which returns a
+// string or a fmt.Stringer.
+func callString(v reflect.Value, name string) (string, bool) {
+	m := v.MethodByName(name)
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return "", false
+	}
+	out := m.Call(nil)[0]
+	if out.Kind() == reflect.String {
+		return out.String(), true
+	}
+	if s, ok := out.Interface().(fmt.Stringer); ok {
+		return s.String(), true
+	}
+	return "", false
+}
+
+// callBool calls the method without arguments of the v, which returns a
+// bool.
+func callBool(v reflect.Value, name string) (bool, bool) {
+	m := v.MethodByName(name)
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 || m.Type().Out(0).Kind() != reflect.Bool {
+		return false, false
+	}
+	return m.Call(nil)[0].Bool(), true
+}
+
+func traceFields(traceID, spanID, flags string) []Field {
+	fields := []Field{String(TraceIDKey, traceID)}
+	if spanID != "" && !isZeroID(spanID) {
+		fields = append(fields, String(SpanIDKey, spanID))
+	}
+	return append(fields, String(TraceFlagsKey, flags))
+}
+
+func isZeroID(id string) bool {
+	return strings.Trim(id, "0") == ""
+}
+
+// TraceParent is the W3C trace context of a request, which is parsed from
+// the traceparent and tracestate headers. See
+// https://www.w3.org/TR/trace-context/.
+type TraceParent struct {
+	version byte
+	traceID string
+	spanID  string
+	flags   byte
+	state   string
+}
+
+type traceParentContextKey struct{}
+
+// ParseTraceParent parses the traceparent and tracestate headers, e.g.
+//
+//	00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+//
+// An invalid tracestate is discarded as the specification requires.
+func ParseTraceParent(traceparent, tracestate string) (TraceParent, error) {
+	var tp TraceParent
+	s := strings.TrimSpace(traceparent)
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return tp, fmt.Errorf("invalid traceparent: %q", traceparent)
+	}
+	version, err := parseHexByte(s[0:2])
+	if err != nil || version == 0xff || (version == 0 && len(s) != 55) || (len(s) > 55 && s[55] != '-') {
+		return tp, fmt.Errorf("invalid traceparent version: %q", traceparent)
+	}
+	tp.version = version
+	tp.traceID, tp.spanID = s[3:35], s[36:52]
+	if !isLowerHex(tp.traceID) || isZeroID(tp.traceID) {
+		return TraceParent{}, fmt.Errorf("invalid traceparent trace id: %q", traceparent)
+	}
+	if !isLowerHex(tp.spanID) || isZeroID(tp.spanID) {
+		return TraceParent{}, fmt.Errorf("invalid traceparent parent id: %q", traceparent)
+	}
+	if tp.flags, err = parseHexByte(s[53:55]); err != nil {
+		return TraceParent{}, fmt.Errorf("invalid traceparent flags: %q", traceparent)
+	}
+	tp.state, _ = parseTraceState(tracestate)
+	return tp, nil
+}
+
+// TraceParentFromHeaders parses the traceparent and tracestate headers of
+// the incoming request.
+func TraceParentFromHeaders(h http.Header) (TraceParent, error) {
+	return ParseTraceParent(h.Get(TraceParentHeader), strings.Join(h.Values(TraceStateHeader), ","))
+}
+
+// ContextWithTraceParent returns a copy of the ctx which carries the trace
+// parent, its trace fields are added to the entries logged by the Context
+// methods.
+func ContextWithTraceParent(ctx context.Context, tp TraceParent) context.Context {
+	return context.WithValue(ctx, traceParentContextKey{}, tp)
+}
+
+// ContextWithTraceHeaders returns a copy of the ctx which carries the trace
+// parent of the incoming headers, the ctx is returned as is if the headers
+// have no valid traceparent.
+func ContextWithTraceHeaders(ctx context.Context, h http.Header) context.Context {
+	tp, err := TraceParentFromHeaders(h)
+	if err != nil {
+		return ctx
+	}
+	return ContextWithTraceParent(ctx, tp)
+}
+
+// TraceParentFromContext returns the trace parent carried by the ctx.
+func TraceParentFromContext(ctx context.Context) (TraceParent, bool) {
+	if ctx == nil {
+		return TraceParent{}, false
+	}
+	tp, ok := ctx.Value(traceParentContextKey{}).(TraceParent)
+	return tp, ok
+}
+
+// TraceID returns the trace id in 32 lowercase hex characters.
+func (tp TraceParent) TraceID() string { return tp.traceID }
+
+// SpanID returns the parent id in 16 lowercase hex characters.
+func (tp TraceParent) SpanID() string { return tp.spanID }
+
+// TraceFlags returns the trace flags.
+func (tp TraceParent) TraceFlags() byte { return tp.flags }
+
+// TraceState returns the tracestate.
+func (tp TraceParent) TraceState() string { return tp.state }
+
+// IsSampled reports whether the sampled flag is set.
+func (tp TraceParent) IsSampled() bool { return tp.flags&0x01 != 0 }
+
+// String returns the traceparent header.
+func (tp TraceParent) String() string {
+	if tp.traceID == "" {
+		return ""
+	}
+	return fmt.Sprintf("%02x-%s-%s-%02x", tp.version, tp.traceID, tp.spanID, tp.flags)
+}
+
+func extractTraceParent(ctx context.Context) []Field {
+	tp, ok := TraceParentFromContext(ctx)
+	if !ok || tp.traceID == "" {
+		return nil
+	}
+	return traceFields(tp.traceID, tp.spanID, fmt.Sprintf("%02x", tp.flags))
+}
+
+// parseTraceState parses the tracestate, the list members are trimmed and
+// the empty ones are skipped.
+func parseTraceState(tracestate string) (string, error) {
+	var members []string
+	for _, m := range strings.Split(tracestate, ",") {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		i := strings.IndexByte(m, '=')
+		if i <= 0 || i == len(m)-1 {
+			return "", fmt.Errorf("invalid tracestate member: %q", m)
+		}
+		members = append(members, m)
+	}
+	if len(members) > maxTraceStateMembers {
+		return "", errors.New("too many tracestate members")
+	}
+	return strings.Join(members, ","), nil
+}
+
+func parseHexByte(s string) (byte, error) {
+	if !isLowerHex(s) {
+		return 0, fmt.Errorf("invalid hex: %q", s)
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,136 @@
+package log
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	testTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID      = "00f067aa0ba902b7"
+)
+
+// testOTelTraceID and testOTelSpanContext are shaped like the types of
+// go.opentelemetry.io/otel/trace.
+type testOTelTraceID [16]byte
+
+func (id testOTelTraceID) String() string { return hex.EncodeToString(id[:]) }
+
+type testOTelSpanID [8]byte
+
+func (id testOTelSpanID) String() string { return hex.EncodeToString(id[:]) }
+
+type testOTelSpanContext struct {
+	traceID testOTelTraceID
+	spanID  testOTelSpanID
+	sampled bool
+}
+
+func (sc testOTelSpanContext) TraceID() testOTelTraceID { return sc.traceID }
+
+func (sc testOTelSpanContext) SpanID() testOTelSpanID { return sc.spanID }
+
+func (sc testOTelSpanContext) IsSampled() bool { return sc.sampled }
+
+func (sc testOTelSpanContext) IsValid() bool { return sc.traceID != testOTelTraceID{} }
+
+func TestParseTraceParent(t *testing.T) {
+	tp, err := ParseTraceParent(testTraceParent, " vendor1=a ,, vendor2=b ")
+	assert.NoError(t, err)
+	assert.Equal(t, testTraceID, tp.TraceID())
+	assert.Equal(t, testSpanID, tp.SpanID())
+	assert.Equal(t, byte(1), tp.TraceFlags())
+	assert.True(t, tp.IsSampled())
+	assert.Equal(t, "vendor1=a,vendor2=b", tp.TraceState())
+	assert.Equal(t, testTraceParent, tp.String())
+
+	tp, err = ParseTraceParent(testTraceParent, "invalid")
+	assert.NoError(t, err)
+	assert.Equal(t, "", tp.TraceState())
+
+	// the future versions may have more fields
+	tp, err = ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra", "")
+	assert.NoError(t, err)
+	assert.False(t, tp.IsSampled())
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0x",
+	} {
+		_, err = ParseTraceParent(invalid, "")
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestContextWithTraceHeaders(t *testing.T) {
+	h := http.Header{}
+	ctx := context.Background()
+	assert.Equal(t, ctx, ContextWithTraceHeaders(ctx, h))
+
+	h.Set(TraceParentHeader, testTraceParent)
+	h.Add(TraceStateHeader, "vendor1=a")
+	h.Add(TraceStateHeader, "vendor2=b")
+	tp, ok := TraceParentFromContext(ContextWithTraceHeaders(ctx, h))
+	assert.True(t, ok)
+	assert.Equal(t, testTraceID, tp.TraceID())
+	assert.Equal(t, "vendor1=a,vendor2=b", tp.TraceState())
+}
+
+func TestContextExtractors(t *testing.T) {
+	ctx := ContextWithTraceHeaders(context.Background(), http.Header{
+		"Traceparent": []string{testTraceParent},
+	})
+	assert.Equal(t, []Field{
+		String(TraceIDKey, testTraceID),
+		String(SpanIDKey, testSpanID),
+		String(TraceFlagsKey, "01"),
+	}, extractContext(ctx))
+	assert.Nil(t, extractContext(context.Background()))
+
+	type otelKey struct{}
+	RegisterContextExtractor("otel", SpanContextExtractor(func(ctx context.Context) interface{} {
+		return ctx.Value(otelKey{})
+	}))
+	defer UnregisterContextExtractor("otel")
+	UnregisterContextExtractor(W3CTraceExtractor)
+	defer RegisterContextExtractor(W3CTraceExtractor, extractTraceParent)
+
+	var traceID testOTelTraceID
+	_, _ = hex.Decode(traceID[:], []byte(testTraceID))
+	sc := testOTelSpanContext{traceID: traceID, spanID: testOTelSpanID{1}}
+	ctx = context.WithValue(ctx, otelKey{}, sc)
+	assert.Equal(t, []Field{
+		String(TraceIDKey, testTraceID),
+		String(SpanIDKey, "0100000000000000"),
+		String(TraceFlagsKey, "00"),
+	}, extractContext(ctx))
+
+	ctx = context.WithValue(ctx, otelKey{}, testOTelSpanContext{})
+	assert.Nil(t, extractContext(ctx))
+
+	t.Run("logger", func(t *testing.T) {
+		RegisterContextExtractor("static", func(ctx context.Context) []Field {
+			return []Field{String("tenant", "acme")}
+		})
+		defer UnregisterContextExtractor("static")
+
+		l := newContextTestLogger(t)
+		ctx := context.WithValue(context.Background(), otelKey{}, testOTelSpanContext{traceID: traceID, sampled: true})
+		l.InfoContext(WithContextFields(ctx, String("request_id", "r1")), "hello")
+		lines := readContextTestLogger(t, l)
+		assert.Equal(t, []string{
+			`{"level":"INFO","caller":"log/trace_test.go:129","msg":"hello",` +
+				`"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","trace_flags":"01","tenant":"acme","request_id":"r1"}`,
+		}, lines)
+	})
+}
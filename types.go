@@ -23,6 +23,9 @@ type CallerEncoder = zapcore.CallerEncoder
 // LevelEncoder is an alias for the zapcore.LevelEncoder.
 type LevelEncoder = zapcore.LevelEncoder
 
+// Clock is an alias for the zapcore.Clock.
+type Clock = zapcore.Clock
+
 // Alias for zap log level.
 var (
 	// DebugLevel logs are typically voluminous, and are usually disabled in
@@ -60,8 +63,6 @@ var (
 	Complex128s = zap.Complex128s
 	Duration    = zap.Duration
 	Durations   = zap.Durations
-	Err         = zap.Error
-	Errors      = zap.Errors
 	Float32     = zap.Float32
 	Float32s    = zap.Float32s
 	Float64     = zap.Float64